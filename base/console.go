@@ -5,6 +5,7 @@ import (
   "github.com/MobRulesGames/glop/gui"
   "bufio"
   "github.com/MobRulesGames/opengl/gl"
+  "sort"
   "strings"
   "unicode"
 )
@@ -13,7 +14,8 @@ const maxLines = 25
 const maxLineLength = 150
 
 // A simple gui element that will display the last several lines of text from
-// a log file (TODO: and also allow you to enter some basic commands).
+// a log file and allow you to enter the commands registered with
+// RegisterConsoleCommand.
 type Console struct {
   gui.BasicZone
   lines      [maxLines]string
@@ -25,6 +27,34 @@ type Console struct {
   dict  *gui.Dictionary
 }
 
+var console_commands = make(map[string]func(args []string))
+
+// Registers f to be called whenever a line starting with name is entered in
+// the console, args are the rest of the words on the line.
+func RegisterConsoleCommand(name string, f func(args []string)) {
+  console_commands[name] = f
+}
+
+func (c *Console) runCommand() {
+  words := strings.Fields(string(c.cmd))
+  c.cmd = c.cmd[0:0]
+  if len(words) == 0 {
+    return
+  }
+  Log().Printf("Console: %s", strings.Join(words, " "))
+  f, ok := console_commands[words[0]]
+  if !ok {
+    var names []string
+    for name := range console_commands {
+      names = append(names, name)
+    }
+    sort.Strings(names)
+    Warn().Printf("Unknown command '%s', the commands are %v", words[0], names)
+    return
+  }
+  f(words[1:])
+}
+
 func MakeConsole() *Console {
   if log_console == nil {
     panic("Cannot make a console until the logging system has been set up.")
@@ -74,6 +104,22 @@ func (c *Console) Respond(ui *gui.Gui, group gui.EventGroup) bool {
     c.xscroll = 0
   }
 
+  // Only take commands while the console is open, otherwise everything
+  // typed while playing would end up in them.
+  if !group.Focus {
+    return false
+  }
+  if found, event := group.FindEvent(gin.Return); found && event.Type == gin.Press {
+    c.runCommand()
+    return true
+  }
+  if found, event := group.FindEvent(gin.DeleteOrBackspace); found && event.Type == gin.Press {
+    if len(c.cmd) > 0 {
+      c.cmd = c.cmd[0 : len(c.cmd)-1]
+    }
+    return true
+  }
+
   if group.Events[0].Type == gin.Press {
     r := rune(group.Events[0].Key.Id())
     if r < 256 {
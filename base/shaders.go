@@ -74,6 +74,11 @@ func SetUniformF(shader, variable string, f float32) {
 }
 
 func InitShaders() {
+  if Headless() {
+    shader_progs = make(map[string]uint32)
+    warned_names = make(map[string]bool)
+    return
+  }
   render.Queue(func() {
     vertex_shaders = make(map[string]uint32)
     fragment_shaders = make(map[string]uint32)
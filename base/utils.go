@@ -34,6 +34,18 @@ func GetDataDir() string {
   return datadir
 }
 
+var headless bool
+
+// When running headless there is no window and no OpenGL context, nothing
+// queued on the render thread will ever run.  This must be set before
+// anything is loaded.
+func SetHeadless(_headless bool) {
+  headless = _headless
+}
+func Headless() bool {
+  return headless
+}
+
 func setupLogger() {
   // If an error happens when making this directory it might already exist,
   // all that really matters is making the log file in the directory.
@@ -162,6 +162,9 @@ end
 function RoundStart(intruders, round)
   print("SCRIPT: Round Start")
   store.execs = {}
+  if Net.Active() and checkForfeit("ui/dialog/Lvl01/Victory_") then
+    return
+  end
   if round == 1 then
     if intruders then
       intrudersSetup() 
@@ -382,6 +385,41 @@ function OnAction(intruders, round, exec)
 end
  
 
+-- Plays back a single exec from the other side's turn, this is also used by
+-- the replay viewer to step through a game one exec at a time.
+function PlaybackExec(exec)
+  print("SCRIPT: proc exec")
+  bDone = false
+  if exec.script_spawn then
+    print("SRCIPT: Exec - Spawn")
+    doSpawn(exec)
+    bDone = true
+  end
+  if exec.script_despawn then
+    print("SRCIPT: Exec - Despawn")
+    deSpawn(exec)
+    bDone = true
+  end
+  if exec.script_waypoint then
+    print("SRCIPT: Exec - Waypoint")
+    doWaypoint(exec)
+    bDone = true
+  end
+  if not bDone then
+    print("SRCIPT: Exec - Standard")
+    Script.DoExec(exec)
+
+    --will be used at turn start to try to reselect the last thing they acted with.
+    if exec.Ent.Side == "intruders" then
+      store.LastIntruderEnt = exec.Ent
+    end
+    if exec.Ent.Side == "denizens" then
+      store.LastDenizenEnt = exec.Ent
+    end
+  end
+  checkExec(exec, true)
+end
+
 function DoPlayback(state, execs)
   Script.LoadGameState(state)
 
@@ -390,36 +428,7 @@ function DoPlayback(state, execs)
   Script.FocusPos(GetEntityWithMostAP(side2).Pos)
 
   for _, exec in pairs(execs) do
-    print("SCRIPT: proc exec")
-    bDone = false
-    if exec.script_spawn then
-      print("SRCIPT: Exec - Spawn")
-      doSpawn(exec)
-      bDone = true
-    end
-    if exec.script_despawn then
-      print("SRCIPT: Exec - Despawn")
-      deSpawn(exec)
-      bDone = true
-    end
-    if exec.script_waypoint then
-      print("SRCIPT: Exec - Waypoint")
-      doWaypoint(exec)
-      bDone = true
-    end
-    if not bDone then
-      print("SRCIPT: Exec - Standard")
-      Script.DoExec(exec)
-
-      --will be used at turn start to try to reselect the last thing they acted with.
-      if exec.Ent.Side == "intruders" then
-        store.LastIntruderEnt = exec.Ent
-      end
-      if exec.Ent.Side == "denizens" then
-        store.LastDenizenEnt = exec.Ent
-      end
-    end
-    checkExec(exec, true)
+    PlaybackExec(exec)
   end
   print("SCRIPT: Playback complete")
 end
@@ -464,9 +473,13 @@ function RoundEnd(intruders, round)
     Net.UpdateExecs(Script.SaveGameState(), store.execs)
     Script.ShowMainBar(false)
     Net.Wait()
+    if checkForfeit("ui/dialog/Lvl01/Victory_") then
+      return
+    end
     -- cur = Script.SaveGameState()
     state, execs = Net.LatestStateAndExecs()
     DoPlayback(state, execs)
+    Net.CheckSync()
     Script.ShowMainBar(true)
     return
   end
@@ -104,6 +104,9 @@ end
 
 
 function RoundStart(intruders, round)
+  if Net.Active() and checkForfeit("ui/dialog/Lvl02/Lvl_02_Victory_") then
+    return
+  end
   if store.execs == nil then
     store.execs = {}
   end
@@ -278,9 +281,13 @@ function RoundEnd(intruders, round)
     Net.UpdateExecs(Script.SaveGameState(), store.execs)
     Script.ShowMainBar(false)
     Net.Wait()
+    if checkForfeit("ui/dialog/Lvl02/Lvl_02_Victory_") then
+      return
+    end
     -- cur = Script.SaveGameState()
     state, execs = Net.LatestStateAndExecs()
     DoPlayback(state, execs)
+    Net.CheckSync()
     Script.ShowMainBar(true)
     return
   end
@@ -327,6 +334,39 @@ function RoundEnd(intruders, round)
   end
 end
 
+-- Plays back a single exec from the other side's turn, this is also used by
+-- the replay viewer to step through a game one exec at a time.
+function PlaybackExec(exec)
+  bDone = false
+  if exec.script_spawn then
+    doSpawn(exec)
+    bDone = true
+  end
+  if exec.script_despawn then
+    deSpawn(exec)
+    bDone = true
+  end  
+  if exec.script_waypoint then
+    doWaypoint(exec)
+    bDone = true
+  end  
+  if exec.script_damage then
+    doDamage(exec)
+    bDone = true
+  end                 
+  if not bDone then
+    Script.DoExec(exec)
+
+    --will be used at turn start to try to reselect the last thing they acted with.
+    if exec.Ent.Side == "intruders" then
+      store.LastIntruderEnt = exec.Ent
+    end 
+    if exec.Ent.Side == "denizens" then
+      store.LastDenizenEnt = exec.Ent
+    end 
+  end
+end
+
 function DoPlayback(state, execs)
   Script.LoadGameState(state)
 
@@ -335,34 +375,7 @@ function DoPlayback(state, execs)
   Script.FocusPos(GetEntityWithMostAP(side2).Pos)
 
   for _, exec in pairs(execs) do
-    bDone = false
-    if exec.script_spawn then
-      doSpawn(exec)
-      bDone = true
-    end
-    if exec.script_despawn then
-      deSpawn(exec)
-      bDone = true
-    end  
-    if exec.script_waypoint then
-      doWaypoint(exec)
-      bDone = true
-    end  
-    if exec.script_damage then
-      doDamage(exec)
-      bDone = true
-    end                 
-    if not bDone then
-      Script.DoExec(exec)
-
-      --will be used at turn start to try to reselect the last thing they acted with.
-      if exec.Ent.Side == "intruders" then
-        store.LastIntruderEnt = exec.Ent
-      end 
-      if exec.Ent.Side == "denizens" then
-        store.LastDenizenEnt = exec.Ent
-      end 
-    end
+    PlaybackExec(exec)
   end
 end
 
@@ -147,6 +147,9 @@ function denizensSetup()
 end
 
 function RoundStart(intruders, round)
+  if Net.Active() and checkForfeit("ui/dialog/Lvl03/Lvl_03_Victory_") then
+    return
+  end
   side = {Intruder = intruders, Denizen = not intruders, Npc = false, Object = false}
 
   Script.SetLosMode("intruders", "entities")
@@ -421,6 +424,50 @@ function checkExec(exec, is_playback)
   end
 end
 
+-- Plays back a single exec from the other side's turn, this is also used by
+-- the replay viewer to step through a game one exec at a time.
+function PlaybackExec(exec)
+  bDone = false
+  if exec.script_spawn then
+    doSpawn(exec)
+    bDone = true
+  end
+  if exec.script_despawn then
+    deSpawn(exec)
+    bDone = true
+  end
+  if exec.script_gear then
+    doGear(exec)
+    bDone = true
+  end
+  if exec.script_condition then
+    doCondition(exec)
+    bDone = true
+  end     
+  if exec.script_waypoint then
+    doWaypoint(exec)
+    bDone = true
+  end      
+  if not bDone then
+    for k, v in pairs(exec) do
+    end
+    Script.DoExec(exec)
+
+    --will be used at turn start to try to reselect the last thing they acted with.
+    if exec.Ent then
+      if exec.Ent.Side == "intruders" then
+        store.LastIntruderEnt = exec.Ent
+      end 
+      if exec.Ent.Side == "denizens" then
+        store.LastDenizenEnt = exec.Ent
+      end 
+    end
+  end
+  if exec.Ent then
+    checkExec(exec, true)
+  end
+end
+
 function DoPlayback(state, execs)
   Script.LoadGameState(state)
 
@@ -428,46 +475,8 @@ function DoPlayback(state, execs)
   side2 = {Intruder = not intruders, Denizen = intruders, Npc = false, Object = false}  --reversed because it's still one side's turn when we're replaying their actions for the other side.
   Script.FocusPos(GetEntityWithMostAP(side2).Pos)
 
-  for i, exec in pairs(execs) do
-    bDone = false
-    if exec.script_spawn then
-      doSpawn(exec)
-      bDone = true
-    end
-    if exec.script_despawn then
-      deSpawn(exec)
-      bDone = true
-    end
-    if exec.script_gear then
-      doGear(exec)
-      bDone = true
-    end
-    if exec.script_condition then
-      doCondition(exec)
-      bDone = true
-    end     
-    if exec.script_waypoint then
-      doWaypoint(exec)
-      bDone = true
-    end      
-    if not bDone then
-      for k, v in pairs(exec) do
-      end
-      Script.DoExec(exec)
-
-      --will be used at turn start to try to reselect the last thing they acted with.
-      if exec.Ent then
-        if exec.Ent.Side == "intruders" then
-          store.LastIntruderEnt = exec.Ent
-        end 
-        if exec.Ent.Side == "denizens" then
-          store.LastDenizenEnt = exec.Ent
-        end 
-      end
-    end
-    if exec.Ent then
-      checkExec(exec, true)
-    end
+  for _, exec in pairs(execs) do
+    PlaybackExec(exec)
   end
 end
 
@@ -476,9 +485,13 @@ function RoundEnd(intruders, round)
     Net.UpdateExecs(Script.SaveGameState(), store.execs)
     Script.ShowMainBar(false)
     Net.Wait()
+    if checkForfeit("ui/dialog/Lvl03/Lvl_03_Victory_") then
+      return
+    end
     -- cur = Script.SaveGameState()
     state, execs = Net.LatestStateAndExecs()
     DoPlayback(state, execs)
+    Net.CheckSync()
     Script.ShowMainBar(true)
     return
   end
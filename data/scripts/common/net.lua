@@ -0,0 +1,16 @@
+-- Functions shared by every level that can be played online.  This is loaded
+-- before the level's own script.
+
+-- In an online game a player that misses their turn deadline forfeits, in
+-- which case the other player has won.  victory is the path to the level's
+-- victory dialogs up to the name of the winning side, e.g.
+-- "ui/dialog/Lvl01/Victory_".  Returns true if the game is over.
+function checkForfeit(victory)
+  winner = Net.Forfeit()
+  if not winner then
+    return false
+  end
+  Script.DialogBox(victory .. winner .. ".json")
+  Script.EndGame()
+  return true
+end
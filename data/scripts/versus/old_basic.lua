@@ -224,11 +224,10 @@ function OnMove(ent, path)
 end
 
 function SelectSpawn(SpawnName)
-  math.randomseed(os.time())
   possible_spawns = Script.GetSpawnPointsMatching(SpawnName)
   bUsedOne = false   
   for _, spawn in pairs(possible_spawns) do
-    if math.random(4) > 2 then
+    if Script.Rand(4) > 2 then
       return spawn
     end 
   end  
@@ -0,0 +1,8 @@
+-- Lets every denizen take its turn, one after another.
+function Think()
+  for _, ent in pairs(AllDenizens()) do
+    while IsActive(ent) do
+      ExecDenizen(ent)
+    end
+  end
+end
@@ -0,0 +1,8 @@
+-- Lets every intruder take its turn, one after another.
+function Think()
+  for _, ent in pairs(AllIntruders()) do
+    while IsActive(ent) do
+      ExecIntruder(ent)
+    end
+  end
+end
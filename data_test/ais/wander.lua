@@ -0,0 +1,10 @@
+-- Takes a few steps in random directions.
+function Think()
+  for i = 1, 3 do
+    pos = Me.Pos
+    dst = {X = pos.X + randN(5) - 3, Y = pos.Y + randN(5) - 3}
+    if not Do.Move({dst}, 2) then
+      return
+    end
+  end
+end
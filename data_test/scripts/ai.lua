@@ -0,0 +1,36 @@
+-- A level for tests of games that the Ais play on their own.  Both sides are
+-- spawned at random in their spawn points and every ent wanders around at
+-- random, so two games only play out the same way if they were seeded the
+-- same way.
+
+function OnStartup()
+end
+
+function Init(data)
+  Script.LoadHouse("test")
+  Script.BindAi("denizen", "denizens.lua")
+  Script.BindAi("intruder", "intruders.lua")
+  Script.SetLosMode("intruders", "entities")
+  Script.SetLosMode("denizens", "entities")
+  intruder_spawns = Script.GetSpawnPointsMatching("Intruders_Start")
+  denizen_spawns = Script.GetSpawnPointsMatching("Denizens_Start")
+  for i = 1, 3 do
+    ent = Script.SpawnEntitySomewhereInSpawnPoints("Test Intruder", intruder_spawns, false)
+    Script.BindAi(ent, "wander.lua")
+    ent = Script.SpawnEntitySomewhereInSpawnPoints("Test Denizen", denizen_spawns, false)
+    Script.BindAi(ent, "wander.lua")
+  end
+end
+
+function RoundStart(intruders, round)
+end
+
+function OnMove(ent, path)
+  return table.getn(path)
+end
+
+function OnAction(intruders, round, exec)
+end
+
+function RoundEnd(intruders, round)
+end
@@ -0,0 +1,48 @@
+-- A level for scenario tests.  Both sides are played by the test, it spawns
+-- one intruder and one denizen and the denizens win as soon as every
+-- intruder is dead.  Like the levels that can be played online it keeps the
+-- execs of the current turn in store.execs.
+
+function AnyIntrudersAlive()
+  for _, ent in pairs(Script.GetAllEnts()) do
+    if ent.Side.Intruder and ent.HpCur > 0 then
+      return true
+    end
+  end
+  return false
+end
+
+function OnStartup()
+end
+
+function Init(data)
+  Script.LoadHouse("test")
+  Script.BindAi("denizen", "human")
+  Script.BindAi("intruder", "human")
+  Script.SetLosMode("intruders", "entities")
+  Script.SetLosMode("denizens", "entities")
+  Script.SpawnEntityAtPosition("Test Intruder", {X = 2, Y = 2})
+  Script.SpawnEntityAtPosition("Test Denizen", {X = 6, Y = 2})
+  store.actions = 0
+  store.execs = {}
+end
+
+function RoundStart(intruders, round)
+  store.execs = {}
+end
+
+function OnMove(ent, path)
+  return table.getn(path)
+end
+
+function OnAction(intruders, round, exec)
+  store.actions = store.actions + 1
+  store.execs[table.getn(store.execs) + 1] = exec
+  if not AnyIntrudersAlive() then
+    Script.DialogBox("ui/dialog/Victory_Denizens.json")
+    Script.EndGame()
+  end
+end
+
+function RoundEnd(intruders, round)
+end
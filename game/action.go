@@ -48,7 +48,8 @@ const (
   // The Action is in progress and should not be interrupted.
   InProgress MaintenanceStatus = iota
 
-  // The Action is interrupted and can be interrupted immediately.
+  // The Action is in progress but is at a point where it can be interrupted,
+  // readied actions get a chance to go off before it continues.
   CheckForInterrupts
 
   // The Action has been completed.
@@ -60,6 +61,10 @@ const (
 type BasicActionExec struct {
   Ent   EntityId
   Index int
+
+  // Only set on execs that are an ent's readied action going off as an
+  // interrupt, this is the action that was readied.
+  Readied *ReadiedAction
 }
 
 func (bae BasicActionExec) EntityId() EntityId {
@@ -87,6 +92,12 @@ func (bae BasicActionExec) GetPath() []int {
   return nil
 }
 func (bae BasicActionExec) TruncatePath(int) {}
+func (bae BasicActionExec) ReadiedAction() *ReadiedAction {
+  return bae.Readied
+}
+func (bae *BasicActionExec) SetReadiedAction(readied *ReadiedAction) {
+  bae.Readied = readied
+}
 func (bae *BasicActionExec) SetBasicData(ent *Entity, action Action) {
   bae.Ent = ent.Id
   bae.Index = -1
@@ -113,6 +124,12 @@ type ActionExec interface {
 
   GetPath() []int
   TruncatePath(length int)
+
+  // If this exec is a readied action going off as an interrupt these get and
+  // set what was readied.  It is part of the exec so that playing the exec
+  // back uses up the readied action just like it did when it was made.
+  ReadiedAction() *ReadiedAction
+  SetReadiedAction(readied *ReadiedAction)
 }
 
 func encodeActionExec(ae ActionExec) []byte {
@@ -182,10 +199,12 @@ type Action interface {
   // interrupted.
   Maintain(dt int64, g *Game, exec ActionExec) MaintenanceStatus
 
-  // This will be called if the action has been readied at this is a logical
-  // point for an interrupt to happen.  Should return true if the action
-  // should take place.
-  Interrupt() bool
+  // This will be called if ent has readied this action and target, an enemy
+  // of ent, has just moved within view of it.  Returns the exec that ent
+  // should perform against target, or nil if the action shouldn't take
+  // place.  The exec is performed once the action that was interrupted has
+  // been cut short.
+  Interrupt(ent, target *Entity, g *Game) ActionExec
 
   // Pushes a table containing information about the action onto the stack.
   Push(L *lua.State)
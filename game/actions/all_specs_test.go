@@ -8,5 +8,9 @@ import (
 func TestAllSpecs(t *testing.T) {
   r := gospec.NewRunner()
   r.AddSpec(ActionSpec)
+  r.AddSpec(InterruptSpec)
+  r.AddSpec(RandSpec)
+  r.AddSpec(ScenarioSpec)
+  r.AddSpec(VerifySpec)
   gospec.MainGoTest(r, t)
 }
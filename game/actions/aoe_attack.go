@@ -26,6 +26,7 @@ func registerAoeAttacks() map[string]func() game.Action {
     makers[cname] = func() game.Action {
       a := AoeAttack{Defname: cname}
       base.GetObject("actions-aoe_actions", &a)
+      a.check(a.Name)
       if a.Ammo > 0 {
         a.Current_ammo = a.Ammo
       } else {
@@ -64,6 +65,7 @@ type AoeAttackDef struct {
   Conditions []string
   Texture    texture.Object
   Sounds     map[string]string
+  AttackDiceDef
 }
 type aoeAttackTempData struct {
   ent *game.Entity
@@ -75,6 +77,12 @@ type aoeAttackTempData struct {
   // All entities in the blast radius - could include the acting entity
   targets []*game.Entity
 
+  // The ents that would be hit if the aoe were centered on the position it
+  // was last previewed at, so that they aren't found again every frame.
+  preview_x, preview_y int
+  preview_targets      []*game.Entity
+  previewed            bool
+
   exec *aoeExec
 }
 type aoeExec struct {
@@ -199,6 +207,31 @@ func (a *AoeAttack) RenderOnFloor() {
   (&texture.Object{}).Data().Render(float64(x), float64(y), float64(a.Diameter), float64(a.Diameter))
   base.EnableShader("")
 }
+// Returns the odds of ent hitting target with this attack, whether or not
+// target would be in the blast.
+func (a *AoeAttack) AttackOdds(ent, target *game.Entity) game.AttackOdds {
+  return game.CalcAttackOdds(ent, target, a.attackSpec(a.Strength, a.Damage, a.Kind))
+}
+func (a *AoeAttack) PreviewAttack(target *game.Entity) (game.AttackOdds, bool) {
+  if a.ent == nil {
+    return game.AttackOdds{}, false
+  }
+  ex, ey := a.ent.Pos()
+  if dist(ex, ey, a.tx, a.ty) > a.Range || !a.ent.HasLos(a.tx, a.ty, 1, 1) {
+    return game.AttackOdds{}, false
+  }
+  if !a.previewed || a.preview_x != a.tx || a.preview_y != a.ty {
+    a.preview_targets = a.getTargetsAt(a.ent.Game(), a.tx, a.ty)
+    a.preview_x, a.preview_y = a.tx, a.ty
+    a.previewed = true
+  }
+  for _, t := range a.preview_targets {
+    if t == target {
+      return a.AttackOdds(a.ent, target), true
+    }
+  }
+  return game.AttackOdds{}, false
+}
 func (a *AoeAttack) Cancel() {
   a.aoeAttackTempData = aoeAttackTempData{}
 }
@@ -374,12 +407,17 @@ func (a *AoeAttack) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.Ma
     target.TurnToFace(a.ent.Pos())
   }
   a.ent.Sprite().Command(a.Animation)
+  spec := a.attackSpec(a.Strength, a.Damage, a.Kind)
   for _, target := range a.targets {
-    if g.DoAttack(a.ent, target, a.Strength, a.Kind) {
+    res := g.ResolveAttack(a.ent, target, spec)
+    if res.Hit {
       for _, name := range a.Conditions {
         target.Stats.ApplyCondition(status.MakeCondition(name))
       }
-      target.Stats.ApplyDamage(0, -a.Damage, a.Kind)
+    }
+    a.applyResultConditions(res, a.ent, target)
+    if res.Hit {
+      target.Stats.ApplyDamage(0, -res.Damage, a.Kind)
       if target.Stats.HpCur() <= 0 {
         target.Sprite().CommandN([]string{"defend", "killed"})
       } else {
@@ -391,6 +429,17 @@ func (a *AoeAttack) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.Ma
   }
   return game.Complete
 }
-func (a *AoeAttack) Interrupt() bool {
-  return true
+func (a *AoeAttack) Interrupt(ent, target *game.Entity, g *game.Game) game.ActionExec {
+  if a.Current_ammo == 0 {
+    return nil
+  }
+  ex, ey := ent.Pos()
+  x, y := target.Pos()
+  if dist(ex, ey, x, y) > a.Range || !ent.HasLos(x, y, 1, 1) {
+    return nil
+  }
+  var exec aoeExec
+  exec.SetBasicData(ent, a)
+  exec.X, exec.Y = x, y
+  return &exec
 }
@@ -28,6 +28,7 @@ func registerBasicAttacks() map[string]func() game.Action {
       if !a.Target_allies && !a.Target_enemies {
         base.Error().Printf("Basic Attack '%s' cannot target anything!  Either Target_allies or Target_enemies must be true", a.Name)
       }
+      a.check(a.Name)
       if a.Ammo > 0 {
         a.Current_ammo = a.Ammo
       } else {
@@ -67,6 +68,7 @@ type BasicAttackDef struct {
   Conditions     []string
   Texture        texture.Object
   Sounds         map[string]string
+  AttackDiceDef
 }
 type basicAttackTempData struct {
   ent *game.Entity
@@ -96,6 +98,28 @@ func (exec basicAttackExec) Push(L *lua.State, g *game.Game) {
   L.PushString("Target")
   game.LuaPushEntity(L, target)
   L.SetTable(-3)
+  res, ok := results[exec.id]
+  if !ok {
+    return
+  }
+  L.PushString("Result")
+  L.NewTable()
+  L.PushString("Hit")
+  L.PushBoolean(res.Hit)
+  L.SetTable(-3)
+  L.PushString("Roll")
+  L.PushInteger(res.Roll)
+  L.SetTable(-3)
+  L.PushString("Crit")
+  L.PushBoolean(res.Crit)
+  L.SetTable(-3)
+  L.PushString("Fumble")
+  L.PushBoolean(res.Fumble)
+  L.SetTable(-3)
+  L.PushString("Damage")
+  L.PushInteger(res.Damage)
+  L.SetTable(-3)
+  L.SetTable(-3)
 }
 
 func (a *BasicAttack) SoundMap() map[string]string {
@@ -134,6 +158,14 @@ func (a *BasicAttack) Push(L *lua.State) {
 // Results - used by the ai to get feedback on what its actions did.
 type BasicAttackResult struct {
   Hit bool
+
+  // What was rolled to hit, and whether that was a critical hit or a fumble.
+  Roll   int
+  Crit   bool
+  Fumble bool
+
+  // Hp the target actually lost.
+  Damage int
 }
 
 var exec_id int
@@ -235,6 +267,17 @@ func (a *BasicAttack) AiAttackTarget(ent *game.Entity, target *game.Entity) game
   }
   return a.makeExec(ent, target)
 }
+// Returns the odds of ent hitting target with this attack, whether or not
+// target is in range.
+func (a *BasicAttack) AttackOdds(ent, target *game.Entity) game.AttackOdds {
+  return game.CalcAttackOdds(ent, target, a.attackSpec(a.Strength, a.Damage, a.Kind))
+}
+func (a *BasicAttack) PreviewAttack(target *game.Entity) (game.AttackOdds, bool) {
+  if a.ent == nil || !a.validTarget(a.ent, target) {
+    return game.AttackOdds{}, false
+  }
+  return a.AttackOdds(a.ent, target), true
+}
 func (a *BasicAttack) makeExec(ent, target *game.Entity) *basicAttackExec {
   var exec basicAttackExec
   exec.id = exec_id
@@ -302,27 +345,36 @@ func (a *BasicAttack) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.
     }
     a.ent.Stats.ApplyDamage(-a.Ap, 0, status.Unspecified)
     var defender_cmds []string
-    if g.DoAttack(a.ent, a.target, a.Strength, a.Kind) {
+    res := g.ResolveAttack(a.ent, a.target, a.attackSpec(a.Strength, a.Damage, a.Kind))
+    result := BasicAttackResult{Hit: res.Hit, Roll: res.Roll, Crit: res.Crit, Fumble: res.Fumble}
+    if res.Hit {
       for _, name := range a.Conditions {
         a.target.Stats.ApplyCondition(status.MakeCondition(name))
       }
-      a.target.Stats.ApplyDamage(0, -a.Damage, a.Kind)
+    }
+    a.applyResultConditions(res, a.ent, a.target)
+    if res.Hit {
+      hp := a.target.Stats.HpCur()
+      a.target.Stats.ApplyDamage(0, -res.Damage, a.Kind)
+      result.Damage = hp - a.target.Stats.HpCur()
       if a.target.Stats.HpCur() <= 0 {
         defender_cmds = []string{"defend", "killed"}
       } else {
         defender_cmds = []string{"defend", "damaged"}
       }
-      results[a.exec.id] = BasicAttackResult{Hit: true}
     } else {
       defender_cmds = []string{"defend", "undamaged"}
-      results[a.exec.id] = BasicAttackResult{Hit: false}
     }
+    results[a.exec.id] = result
     sprites := []*sprite.Sprite{a.ent.Sprite(), a.target.Sprite()}
     sprite.CommandSync(sprites, [][]string{[]string{a.Animation}, defender_cmds}, "hit")
     return game.Complete
   }
   return game.InProgress
 }
-func (a *BasicAttack) Interrupt() bool {
-  return true
+func (a *BasicAttack) Interrupt(ent, target *game.Entity, g *game.Game) game.ActionExec {
+  if a.Current_ammo == 0 || !a.validTarget(ent, target) {
+    return nil
+  }
+  return a.makeExec(ent, target)
 }
@@ -0,0 +1,90 @@
+package actions
+
+import (
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/status"
+)
+
+// Optional fields shared by the attack definitions, they let an attack use
+// dice expressions, like "2d6+1", instead of the usual 1d10 to hit and flat
+// damage, and give it critical hits and fumbles.
+type AttackDiceDef struct {
+  // Rolled to hit instead of 1d10.
+  Roll string
+
+  // Rolled for damage instead of using Damage.
+  Damage_roll string
+
+  // A roll to hit of at least Crit_on always hits, does Crit_damage on top
+  // of the usual damage and applies Crit_conditions along with the usual
+  // conditions.  Zero means the attack never crits.
+  Crit_on         int
+  Crit_damage     string
+  Crit_conditions []string
+
+  // A roll to hit of at most Fumble_on always misses and applies
+  // Fumble_conditions to the attacker.  Zero means the attack never fumbles.
+  Fumble_on         int
+  Fumble_conditions []string
+}
+
+// Parses expr, returns def if expr is empty or isn't a valid dice
+// expression.  Invalid expressions are reported when the attack is made, see
+// AttackDiceDef.check.
+func parseDiceOr(expr string, def game.Dice) game.Dice {
+  if expr == "" {
+    return def
+  }
+  d, err := game.ParseDice(expr)
+  if err != nil {
+    return def
+  }
+  return d
+}
+
+// Logs an error for every dice expression in ad that can't be parsed.
+func (ad *AttackDiceDef) check(name string) {
+  fields := map[string]string{
+    "Roll":        ad.Roll,
+    "Damage_roll": ad.Damage_roll,
+    "Crit_damage": ad.Crit_damage,
+  }
+  for field, expr := range fields {
+    if expr == "" {
+      continue
+    }
+    if _, err := game.ParseDice(expr); err != nil {
+      base.Error().Printf("Attack '%s' has an invalid %s: %v", name, field, err)
+    }
+  }
+}
+
+// Returns the AttackSpec for an attack with the given strength, flat damage
+// and kind.
+func (ad *AttackDiceDef) attackSpec(strength, damage int, kind status.Kind) game.AttackSpec {
+  return game.AttackSpec{
+    Strength:    strength,
+    Kind:        kind,
+    Roll:        parseDiceOr(ad.Roll, game.Dice{}),
+    Damage:      parseDiceOr(ad.Damage_roll, game.Dice{Bonus: damage}),
+    Crit_on:     ad.Crit_on,
+    Crit_damage: parseDiceOr(ad.Crit_damage, game.Dice{}),
+    Fumble_on:   ad.Fumble_on,
+  }
+}
+
+// Applies the conditions that go along with res, other than the attack's
+// usual conditions.
+func (ad *AttackDiceDef) applyResultConditions(res game.AttackResult, attacker, defender *game.Entity) {
+  if res.Crit {
+    for _, name := range ad.Crit_conditions {
+      defender.Stats.ApplyCondition(status.MakeCondition(name))
+    }
+  }
+  if res.Fumble {
+    for _, name := range ad.Fumble_conditions {
+      attacker.Stats.ApplyCondition(status.MakeCondition(name))
+    }
+  }
+}
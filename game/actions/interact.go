@@ -478,6 +478,6 @@ func (a *Interact) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.Mai
   }
   return game.Complete
 }
-func (a *Interact) Interrupt() bool {
-  return true
+func (a *Interact) Interrupt(ent, target *game.Entity, g *game.Game) game.ActionExec {
+  return nil
 }
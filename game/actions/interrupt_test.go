@@ -0,0 +1,49 @@
+package actions_test
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/actions"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// Plays a denizens' turn of data_test/scripts/test.lua in which the Test
+// Denizen walks into the Test Intruder's readied attack, then plays the
+// recorded execs back the same way the other player's client would.
+func InterruptSpec(c gospec.Context) {
+  c.Assume(game.SetupHeadless(datadir), Equals, nil)
+  script, err := ioutil.ReadFile(filepath.Join(datadir, "scripts", "test.lua"))
+  c.Assume(err, Equals, nil)
+  s, err := game.MakeScenario("test.lua", nil, 1)
+  c.Assume(err, Equals, nil)
+  defer s.Close()
+  intruder := s.Ent("Test Intruder")
+  denizen := s.Ent("Test Denizen")
+  c.Assume(s.Exec(game.MakeReadyExec(intruder, actionNamed(intruder, "Attack Test"))), Equals, nil)
+  c.Assume(s.EndTurn(), Equals, nil)
+  before, err := s.SaveGameState()
+  c.Assume(err, Equals, nil)
+  move := actionNamed(denizen, "Move Test").(*actions.Move)
+  dst := []int{s.Game().ToVertex(6, 6)}
+  c.Assume(s.Exec(move.AiMoveToPos(denizen, dst, 1000)), Equals, nil)
+  c.Assume(intruder.Readied, IsNil)
+  c.Assume(denizen.Stats.HpCur(), Equals, 1)
+  execs, err := s.Execs()
+  c.Assume(err, Equals, nil)
+  after, err := s.SaveGameState()
+  c.Assume(err, Equals, nil)
+
+  c.Specify("A move cut short by an interrupt only costs the steps taken.", func() {
+    x, y := denizen.Pos()
+    c.Expect([]int{x, y}, ContainsInOrder, []int{6, 3})
+    c.Expect(denizen.Stats.ApCur(), Equals, 9)
+  })
+
+  c.Specify("Playing back a turn with an interrupt ends in the same state.", func() {
+    var v game.TurnVerifier
+    c.Expect(v.VerifyTurn(&mrgnet.Game{Script: script}, before, execs, after), Equals, nil)
+  })
+}
@@ -61,6 +61,19 @@ type Move struct {
 
   // Ap remaining before the ability was used
   threshold int
+
+  // The exec being performed, it can be truncated by an interrupt while the
+  // ent is moving.
+  exec *moveExec
+
+  // Ap it costs to get to each vertex of exec's path, the whole path is paid
+  // for up front and anything past where an interrupt stops the ent is paid
+  // back.
+  costs []int
+
+  // True if the ent is stopped at path[0] waiting to see if it was
+  // interrupted there.
+  at_cell bool
 }
 type MoveDef struct {
   Name    string
@@ -72,27 +85,30 @@ type moveExec struct {
   Path []int
 }
 
-func (exec *moveExec) measureCost(ent *game.Entity, g *game.Game) int {
+// Returns the ap it costs ent to get to each vertex along the path, or nil if
+// the path isn't valid.
+func (exec *moveExec) measureCosts(ent *game.Entity, g *game.Game) []int {
   if len(exec.Path) == 0 {
     base.Error().Printf("Zero length path")
-    return -1
+    return nil
   }
   if g.ToVertex(ent.Pos()) != exec.Path[0] {
     base.Error().Printf("Path doesn't begin at ent's position, %d != %d", g.ToVertex(ent.Pos()), exec.Path[0])
-    return -1
+    return nil
   }
   graph := g.Graph(ent.Side(), true, nil)
   v := g.ToVertex(ent.Pos())
   cost := 0
+  costs := []int{0}
   for _, step := range exec.Path[1:] {
-    dsts, costs := graph.Adjacent(v)
+    dsts, adj_costs := graph.Adjacent(v)
     ok := false
     prev := v
     base.Log().Printf("Adj(%d):", v)
     for j := range dsts {
       base.Log().Printf("Node %d", dsts[j])
       if dsts[j] == step {
-        cost += int(costs[j])
+        cost += int(adj_costs[j])
         v = dsts[j]
         ok = true
         break
@@ -100,10 +116,11 @@ func (exec *moveExec) measureCost(ent *game.Entity, g *game.Game) int {
     }
     base.Log().Printf("%d -> %d: %t", prev, v, ok)
     if !ok {
-      return -1
+      return nil
     }
+    costs = append(costs, cost)
   }
-  return cost
+  return costs
 }
 func (exec *moveExec) Push(L *lua.State, g *game.Game) {
   exec.BasicActionExec.Push(L, g)
@@ -308,6 +325,8 @@ func (a *Move) Cancel() {
   a.ent = nil
   a.path = nil
   a.calculated = false
+  a.exec = nil
+  a.at_cell = false
 }
 func (a *Move) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.MaintenanceStatus {
   if ae != nil {
@@ -317,7 +336,11 @@ func (a *Move) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.Mainten
       base.Error().Printf("Got a move exec with a path length of 0: %v", exec)
       return game.Complete
     }
-    a.cost = exec.measureCost(a.ent, g)
+    a.costs = exec.measureCosts(a.ent, g)
+    a.cost = -1
+    if a.costs != nil {
+      a.cost = a.costs[len(a.costs)-1]
+    }
     if a.cost > a.ent.Stats.ApCur() {
       base.Error().Printf("Got a move that required more ap than available: %v", exec)
       base.Error().Printf("Path: %v", exec.Path)
@@ -341,26 +364,51 @@ func (a *Move) Maintain(dt int64, g *game.Game, ae game.ActionExec) game.Mainten
     })
     base.Log().Printf("Path Validated: %v", exec)
     a.ent.Stats.ApplyDamage(-a.cost, 0, status.Unspecified)
+    a.exec = exec
+    a.at_cell = false
     src := g.ToVertex(a.ent.Pos())
     graph := g.Graph(a.ent.Side(), true, nil)
     a.drawPath(a.ent, g, graph, src)
   }
+  if a.at_cell {
+    // If an interrupt went off while the ent was here the path was cut short
+    // and this is as far as it goes.
+    a.at_cell = false
+    if g.ToVertex(a.path[0][0], a.path[0][1]) == a.exec.Path[len(a.exec.Path)-1] {
+      // The exec is played back with the path cut short, so it is only
+      // charged for the steps that were taken.
+      refund := a.cost - a.costs[len(a.exec.Path)-1]
+      a.ent.Stats.ApplyDamage(refund, 0, status.Unspecified)
+      return a.finish()
+    }
+    a.path = a.path[1:]
+  }
   // Do stuff
   factor := float32(math.Pow(2, a.ent.Walking_speed))
   dist := a.ent.DoAdvance(factor*float32(dt)/200, a.path[0][0], a.path[0][1])
   for dist > 0 {
+    a.ent.Info.RoomsExplored[a.ent.CurrentRoom()] = true
     if len(a.path) == 1 {
-      a.ent.DoAdvance(0, 0, 0)
-      a.ent.Info.RoomsExplored[a.ent.CurrentRoom()] = true
-      a.ent = nil
-      return game.Complete
+      return a.finish()
+    }
+    if g.ToVertex(a.path[0][0], a.path[0][1]) != a.exec.Path[0] {
+      // Every step along the way gives readied enemies a chance to interrupt
+      // the move.
+      a.at_cell = true
+      return game.CheckForInterrupts
     }
     a.path = a.path[1:]
-    a.ent.Info.RoomsExplored[a.ent.CurrentRoom()] = true
     dist = a.ent.DoAdvance(dist, a.path[0][0], a.path[0][1])
   }
   return game.InProgress
 }
-func (a *Move) Interrupt() bool {
-  return true
+func (a *Move) finish() game.MaintenanceStatus {
+  a.ent.DoAdvance(0, 0, 0)
+  a.ent = nil
+  a.exec = nil
+  a.costs = nil
+  return game.Complete
+}
+func (a *Move) Interrupt(ent, target *game.Entity, g *game.Game) game.ActionExec {
+  return nil
 }
@@ -0,0 +1,55 @@
+package actions_test
+
+import (
+  "bytes"
+  "encoding/gob"
+  "time"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  _ "github.com/MobRulesGames/haunts/game/ai"
+)
+
+type entPos struct {
+  Name string
+  X, Y int
+}
+
+// Plays data_test/scripts/ai.lua, seeded with seed, until the Ais have taken
+// turns turns, and returns where every ent ended up and the gobbed game.
+func playAiTurns(seed int64, turns int) (map[game.EntityId]entPos, []byte, error) {
+  h := game.MakeHeadlessGame("ai.lua", nil, nil, game.AutoInput{}, seed)
+  defer h.Close()
+  h.SetClock(game.StepClock{Step: 10})
+  h.SetFastForward(true)
+  if err := h.RunUntil(turns, 10*time.Second); err != nil {
+    return nil, nil, err
+  }
+  positions := make(map[game.EntityId]entPos)
+  for _, ent := range h.Game().Ents {
+    x, y := ent.Pos()
+    positions[ent.Id] = entPos{ent.Name, x, y}
+  }
+  buf := bytes.NewBuffer(nil)
+  if err := gob.NewEncoder(buf).Encode(h.Game()); err != nil {
+    return nil, nil, err
+  }
+  return positions, buf.Bytes(), nil
+}
+
+func RandSpec(c gospec.Context) {
+  c.Assume(game.SetupHeadless(datadir), Equals, nil)
+  c.Specify("Games seeded the same way spawn and play out the same way.", func() {
+    a, a_state, err := playAiTurns(1234, 4)
+    c.Assume(err, Equals, nil)
+    b, b_state, err := playAiTurns(1234, 4)
+    c.Assume(err, Equals, nil)
+    c.Expect(len(a), Equals, 6)
+    c.Expect(len(b), Equals, len(a))
+    for id, pos := range a {
+      c.Expect(b[id], Equals, pos)
+    }
+    c.Expect(len(a_state) > 0, Equals, true)
+    c.Expect(bytes.Equal(a_state, b_state), Equals, true)
+  })
+}
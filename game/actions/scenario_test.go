@@ -0,0 +1,103 @@
+package actions_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/actions"
+)
+
+func actionNamed(ent *game.Entity, name string) game.Action {
+  for _, action := range ent.Actions {
+    if action.String() == name {
+      return action
+    }
+  }
+  return nil
+}
+
+func hasCondition(ent *game.Entity, name string) bool {
+  for _, cond := range ent.Stats.ConditionNames() {
+    if cond == name {
+      return true
+    }
+  }
+  return false
+}
+
+// Plays data_test/scripts/test.lua, which spawns a Test Intruder at (2, 2)
+// and a Test Denizen at (6, 2).
+func ScenarioSpec(c gospec.Context) {
+  c.Assume(game.SetupHeadless(datadir), Equals, nil)
+  s, err := game.MakeScenario("test.lua", nil, 1)
+  c.Assume(err, Equals, nil)
+  defer s.Close()
+  intruder := s.Ent("Test Intruder")
+  denizen := s.Ent("Test Denizen")
+  c.Assume(intruder, Not(IsNil))
+  c.Assume(denizen, Not(IsNil))
+
+  c.Specify("The script spawns its ents.", func() {
+    x, y := intruder.Pos()
+    c.Expect([]int{x, y}, ContainsInOrder, []int{2, 2})
+    x, y = denizen.Pos()
+    c.Expect([]int{x, y}, ContainsInOrder, []int{6, 2})
+    c.Expect(intruder.Stats.HpCur(), Equals, 4)
+    c.Expect(denizen.Stats.HpCur(), Equals, 3)
+    c.Expect(s.Winner(), Equals, "")
+  })
+
+  c.Specify("Moving costs ap and changes position.", func() {
+    move := actionNamed(intruder, "Move Test").(*actions.Move)
+    dst := []int{s.Game().ToVertex(5, 2)}
+    c.Expect(s.Exec(move.AiMoveToPos(intruder, dst, 1000)), Equals, nil)
+    x, y := intruder.Pos()
+    c.Expect([]int{x, y}, ContainsInOrder, []int{5, 2})
+    c.Expect(intruder.Stats.ApCur(), Equals, 7)
+  })
+
+  c.Specify("Attacks cost ap, deal damage and apply conditions.", func() {
+    attack := actionNamed(intruder, "Attack Test").(*actions.BasicAttack)
+    c.Expect(s.Exec(attack.AiAttackTarget(intruder, denizen)), Equals, nil)
+    c.Expect(intruder.Stats.ApCur(), Equals, 6)
+    c.Expect(denizen.Stats.HpCur(), Equals, 1)
+    c.Expect(hasCondition(denizen, "Fire Debuff Attack"), Equals, true)
+  })
+
+  c.Specify("Conditions take effect at the start of their ent's turn.", func() {
+    c.Assume(s.EndTurn(), Equals, nil)
+    attack := actionNamed(denizen, "Attack Test").(*actions.BasicAttack)
+    c.Assume(s.Exec(attack.AiAttackTarget(denizen, intruder)), Equals, nil)
+    c.Expect(intruder.Stats.HpCur(), Equals, 2)
+    c.Assume(s.EndTurn(), Equals, nil)
+    c.Expect(intruder.Stats.HpCur(), Equals, 1)
+    c.Expect(intruder.Stats.ApCur(), Equals, 10)
+  })
+
+  c.Specify("A readied attack interrupts the first enemy that moves in view.", func() {
+    attack := actionNamed(intruder, "Attack Test")
+    c.Assume(s.Exec(game.MakeReadyExec(intruder, attack)), Equals, nil)
+    c.Expect(intruder.Stats.ApCur(), Equals, 6)
+    c.Expect(intruder.Readied, Not(IsNil))
+    c.Assume(s.EndTurn(), Equals, nil)
+    move := actionNamed(denizen, "Move Test").(*actions.Move)
+    dst := []int{s.Game().ToVertex(6, 6)}
+    c.Assume(s.Exec(move.AiMoveToPos(denizen, dst, 1000)), Equals, nil)
+    x, y := denizen.Pos()
+    c.Expect([]int{x, y}, ContainsInOrder, []int{6, 3})
+    c.Expect(denizen.Stats.HpCur(), Equals, 1)
+    c.Expect(intruder.Readied, IsNil)
+    c.Expect(intruder.Stats.ApCur(), Equals, 6)
+  })
+
+  c.Specify("The denizens win once every intruder is dead.", func() {
+    c.Assume(s.EndTurn(), Equals, nil)
+    attack := actionNamed(denizen, "Attack Test").(*actions.BasicAttack)
+    c.Assume(s.Exec(attack.AiAttackTarget(denizen, intruder)), Equals, nil)
+    c.Expect(s.Over(), Equals, false)
+    c.Assume(s.Exec(attack.AiAttackTarget(denizen, intruder)), Equals, nil)
+    c.Expect(intruder.Stats.HpCur(), Equals, 0)
+    c.Expect(s.Over(), Equals, true)
+    c.Expect(s.Winner(), Equals, "Denizens")
+  })
+}
@@ -223,6 +223,6 @@ func (a *SummonAction) Maintain(dt int64, g *game.Game, ae game.ActionExec) game
   }
   return game.InProgress
 }
-func (a *SummonAction) Interrupt() bool {
-  return true
+func (a *SummonAction) Interrupt(ent, target *game.Entity, g *game.Game) game.ActionExec {
+  return nil
 }
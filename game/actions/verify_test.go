@@ -0,0 +1,108 @@
+package actions_test
+
+import (
+  "io/ioutil"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/actions"
+  "github.com/MobRulesGames/haunts/game/status"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "github.com/MobRulesGames/haunts/mrgnet/server"
+)
+
+var identities = make(map[mrgnet.NetId]*mrgnet.Identity)
+
+// Points mrgnet at ts and signs all subsequent requests as id.
+func connectAs(ts *httptest.Server, id mrgnet.NetId) error {
+  err := mrgnet.Configure(mrgnet.Config{Host_url: ts.URL})
+  if err != nil {
+    return err
+  }
+  if _, ok := identities[id]; !ok {
+    identities[id], err = mrgnet.MakeIdentity(id)
+    if err != nil {
+      return err
+    }
+  }
+  mrgnet.SetIdentity(identities[id])
+  return nil
+}
+
+// Plays the denizens' first turn of data_test/scripts/test.lua, in which the
+// Test Denizen moves from (6, 2) to (6, 4), and uploads it to a server that
+// replays every turn with a game.TurnVerifier.
+func VerifySpec(c gospec.Context) {
+  c.Assume(game.SetupHeadless(datadir), Equals, nil)
+  script, err := ioutil.ReadFile(filepath.Join(datadir, "scripts", "test.lua"))
+  c.Assume(err, Equals, nil)
+  dir, err := ioutil.TempDir("", "verify")
+  c.Assume(err, Equals, nil)
+  defer os.RemoveAll(dir)
+  store, err := server.MakeStore(dir)
+  c.Assume(err, Equals, nil)
+  srv := server.MakeServer(store)
+  srv.Verifier = &game.TurnVerifier{}
+  ts := httptest.NewServer(srv)
+  defer ts.Close()
+
+  const denizen = mrgnet.NetId(1234)
+  const intruder = mrgnet.NetId(5678)
+  c.Assume(connectAs(ts, denizen), Equals, nil)
+  var new_game mrgnet.NewGameResponse
+  c.Assume(mrgnet.DoAction("new", mrgnet.NewGameRequest{Id: denizen}, &new_game), Equals, nil)
+  c.Assume(new_game.Err, Equals, "")
+  c.Assume(connectAs(ts, intruder), Equals, nil)
+  var join mrgnet.JoinGameResponse
+  c.Assume(mrgnet.DoAction("join", mrgnet.JoinGameRequest{Id: intruder, Game_key: new_game.Game_key}, &join), Equals, nil)
+  c.Assume(join.Successful, Equals, true)
+
+  s, err := game.MakeScenario("test.lua", nil, 1)
+  c.Assume(err, Equals, nil)
+  defer s.Close()
+  c.Assume(s.EndTurn(), Equals, nil)
+  before, err := s.SaveGameState()
+  c.Assume(err, Equals, nil)
+  ent := s.Ent("Test Denizen")
+  move := actionNamed(ent, "Move Test").(*actions.Move)
+  dst := []int{s.Game().ToVertex(6, 4)}
+  c.Assume(s.Exec(move.AiMoveToPos(ent, dst, 1000)), Equals, nil)
+  execs, err := s.Execs()
+  c.Assume(err, Equals, nil)
+
+  upload := func(after []byte) string {
+    c.Assume(connectAs(ts, denizen), Equals, nil)
+    req := mrgnet.UpdateGameRequest{
+      Id:       denizen,
+      Game_key: new_game.Game_key,
+      Script:   script,
+      Before:   before,
+      Execs:    execs,
+      After:    after,
+    }
+    var resp mrgnet.UpdateGameResponse
+    c.Assume(mrgnet.DoAction("update", req, &resp), Equals, nil)
+    return resp.Err
+  }
+
+  c.Specify("A turn that leads to the state uploaded with it is accepted.", func() {
+    after, err := s.SaveGameState()
+    c.Assume(err, Equals, nil)
+    c.Expect(upload(after), Equals, "")
+  })
+
+  c.Specify("A turn whose state after was tampered with is rejected.", func() {
+    ent.Stats.ApplyDamage(0, -1, status.Unspecified)
+    after, err := s.SaveGameState()
+    c.Assume(err, Equals, nil)
+    c.Expect(upload(after), Not(Equals), "")
+
+    var status_resp mrgnet.StatusResponse
+    c.Assume(mrgnet.DoAction("status", mrgnet.StatusRequest{Id: denizen, Game_key: new_game.Game_key}, &status_resp), Equals, nil)
+    c.Assume(status_resp.Game, Not(IsNil))
+    c.Expect(len(status_resp.Game.Execs), Equals, 0)
+  })
+}
@@ -9,7 +9,6 @@ import (
   "github.com/MobRulesGames/haunts/game"
   lua "github.com/MobRulesGames/golua"
   "io/ioutil"
-  "math/rand"
   "os"
   "path/filepath"
   "strings"
@@ -155,7 +154,7 @@ func (a *Ai) setupLuaState() error {
       base.Error().Printf("Can't call randN with a value <= 0.")
       return 0
     }
-    L.PushInteger(rand.Intn(val) + 1)
+    L.PushInteger(int(a.game.Ai_rand.Int63()%int64(val)) + 1)
     return 1
   })
   a.L.DoString(a.Prog)
@@ -21,6 +21,7 @@ func (a *Ai) addEntityContext() {
   game.LuaPushSmartFunctionTable(a.L, game.FunctionTable{
     "BasicAttack":        func() { a.L.PushGoFunction(DoBasicAttackFunc(a)) },
     "AoeAttack":          func() { a.L.PushGoFunction(DoAoeAttackFunc(a)) },
+    "Ready":              func() { a.L.PushGoFunction(DoReadyFunc(a)) },
     "Move":               func() { a.L.PushGoFunction(DoMoveFunc(a)) },
     "DoorToggle":         func() { a.L.PushGoFunction(DoDoorToggleFunc(a)) },
     "InteractWithObject": func() { a.L.PushGoFunction(DoInteractWithObjectFunc(a)) },
@@ -33,6 +34,7 @@ func (a *Ai) addEntityContext() {
     "AllPathablePoints":          func() { a.L.PushGoFunction(AllPathablePointsFunc(a)) },
     "RangedDistBetweenPositions": func() { a.L.PushGoFunction(RangedDistBetweenPositionsFunc(a)) },
     "RangedDistBetweenEntities":  func() { a.L.PushGoFunction(RangedDistBetweenEntitiesFunc(a)) },
+    "AttackOdds":                 func() { a.L.PushGoFunction(AttackOddsFunc(a)) },
     "NearestNEntities":           func() { a.L.PushGoFunction(NearestNEntitiesFunc(a.ent)) },
     "Waypoints":                  func() { a.L.PushGoFunction(WaypointsFunc(a.ent)) },
     "Exists":                     func() { a.L.PushGoFunction(ExistsFunc(a)) },
@@ -171,7 +173,11 @@ func AllPathablePointsFunc(a *Ai) lua.GoFunction {
 //
 //    Outputs:
 //    res - table - Table containing the following values:
-//                  hit (boolean) - true iff the attack hit its target.
+//                  hit (boolean)    - true iff the attack hit its target.
+//                  roll (integer)   - What was rolled to hit.
+//                  crit (boolean)   - true iff the attack was a critical hit.
+//                  fumble (boolean) - true iff the attack was a fumble.
+//                  damage (integer) - Hp the target lost.
 //                  If the attack was invalid for some reason res will be nil.
 func DoBasicAttackFunc(a *Ai) lua.GoFunction {
   return func(L *lua.State) int {
@@ -207,6 +213,18 @@ func DoBasicAttackFunc(a *Ai) lua.GoFunction {
         L.PushString("hit")
         L.PushBoolean(result.Hit)
         L.SetTable(-3)
+        L.PushString("roll")
+        L.PushInteger(result.Roll)
+        L.SetTable(-3)
+        L.PushString("crit")
+        L.PushBoolean(result.Crit)
+        L.SetTable(-3)
+        L.PushString("fumble")
+        L.PushBoolean(result.Fumble)
+        L.SetTable(-3)
+        L.PushString("damage")
+        L.PushInteger(result.Damage)
+        L.SetTable(-3)
       }
     } else {
       L.PushNil()
@@ -255,6 +273,44 @@ func DoAoeAttackFunc(a *Ai) lua.GoFunction {
   }
 }
 
+// Readies an action, it will be used on the first enemy that moves within
+// view of this entity before its side's next turn.
+//    Format:
+//    res = DoReady(action)
+//
+//    Inputs:
+//    action - string - Name of the action to ready.
+//
+//    Outputs:
+//    res - boolean - true if the action was readied, nil otherwise.
+func DoReadyFunc(a *Ai) lua.GoFunction {
+  return func(L *lua.State) int {
+    if !game.LuaCheckParamsOk(L, "DoReady", game.LuaString) {
+      return 0
+    }
+    me := a.ent
+    name := L.ToString(-1)
+    action := getActionByName(me, name)
+    if action == nil {
+      game.LuaDoError(L, fmt.Sprintf("Entity '%s' (id=%d) has no action named '%s'.", me.Name, me.Id, name))
+      return 0
+    }
+    exec := game.MakeReadyExec(me, action)
+    if exec != nil {
+      a.execs <- exec
+      <-a.pause
+      if me.Readied != nil {
+        L.PushBoolean(true)
+      } else {
+        L.PushNil()
+      }
+    } else {
+      L.PushNil()
+    }
+    return 1
+  }
+}
+
 // Performs an aoe attack against centered at the specified position.
 //    Format:
 //    target = BestAoeAttackPos(attack, extra_dist, spec)
@@ -458,6 +514,63 @@ func RangedDistBetweenEntitiesFunc(a *Ai) lua.GoFunction {
   }
 }
 
+// Computes the odds of this entity hitting a target with one of its attacks.
+// The odds don't depend on range, but cover is worked out from where this
+// entity is standing right now.
+//    Format:
+//    hit, dmg, cover = AttackOdds(attack, target)
+//
+//    Input:
+//    attack - string  - Name of a basic or aoe attack that this entity has.
+//    target - integer - Entity id of the target.
+//
+//    Output:
+//    hit - number - Chance, from 0 to 1, that the attack hits target.
+//    dmg - number - Expected damage, hit times the damage target would take.
+//    cover - integer - Bonus to defense that target gets from cover.
+//    All are nil if this entity can't see target.
+func AttackOddsFunc(a *Ai) lua.GoFunction {
+  return func(L *lua.State) int {
+    if !game.LuaCheckParamsOk(L, "AttackOdds", game.LuaString, game.LuaEntity) {
+      return 0
+    }
+    me := a.ent
+    name := L.ToString(-2)
+    action := getActionByName(me, name)
+    if action == nil {
+      game.LuaDoError(L, fmt.Sprintf("Entity '%s' (id=%d) has no action named '%s'.", me.Name, me.Id, name))
+      return 0
+    }
+    target := game.LuaToEntity(L, me.Game(), -1)
+    if target == nil {
+      game.LuaDoError(L, fmt.Sprintf("Tried to target an entity who doesn't exist."))
+      return 0
+    }
+    var odds game.AttackOdds
+    switch attack := action.(type) {
+    case *actions.BasicAttack:
+      odds = attack.AttackOdds(me, target)
+    case *actions.AoeAttack:
+      odds = attack.AttackOdds(me, target)
+    default:
+      game.LuaDoError(L, fmt.Sprintf("Action '%s' is not an attack.", name))
+      return 0
+    }
+    x, y := target.Pos()
+    dx, dy := target.Dims()
+    if !me.HasLos(x, y, dx, dy) {
+      L.PushNil()
+      L.PushNil()
+      L.PushNil()
+      return 3
+    }
+    L.PushNumber(odds.Hit)
+    L.PushNumber(odds.Damage)
+    L.PushInteger(odds.Cover)
+    return 3
+  }
+}
+
 // Queries whether or not an entity still exists.  An entity existing implies
 // that it currently alive.
 //    Format:
@@ -1054,7 +1167,7 @@ func randFunc(a *Ai) lua.GoFunction {
       return 0
     }
     n := L.ToInteger(-1)
-    L.PushInteger(int(a.game.Rand.Int63()%int64(n)) + 1)
+    L.PushInteger(int(a.game.Ai_rand.Int63()%int64(n)) + 1)
     return 1
   }
 }
@@ -0,0 +1,19 @@
+package game_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  "testing"
+)
+
+func TestAllSpecs(t *testing.T) {
+  r := gospec.NewRunner()
+  r.AddSpec(ChecksumSpec)
+  r.AddSpec(ClockSpec)
+  r.AddSpec(CombatSpec)
+  r.AddSpec(CoverSpec)
+  r.AddSpec(HeadlessSpec)
+  r.AddSpec(JsonStateSpec)
+  r.AddSpec(SaveSlotSpec)
+  r.AddSpec(SaveSpec)
+  gospec.MainGoTest(r, t)
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+  "bytes"
+  "crypto/sha1"
+  "encoding/gob"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "sort"
+  "strings"
+)
+
+// Shown to the player when Net.CheckSync finds that their game doesn't match
+// their opponent's.
+const desync_dialog = "ui/dialog/desync.json"
+
+// Returns a canonical description of everything in the game that affects
+// gameplay, one fact per line.  Two clients that have played the same execs
+// from the same state should always get the same summary, so this is what
+// online games compare to detect desyncs.  Anything that is purely cosmetic,
+// like sprite states or what is selected, is left out, as are the turn and
+// side since those are different for the player that is replaying the turn.
+func (g *Game) stateSummary() []string {
+  var lines []string
+  if g.Rand != nil {
+    buf := bytes.NewBuffer(nil)
+    if err := gob.NewEncoder(buf).Encode(g.Rand); err != nil {
+      base.Warn().Printf("Unable to encode rng for summary: %v", err)
+    }
+    lines = append(lines, fmt.Sprintf("rand %x", sha1.Sum(buf.Bytes())))
+  }
+
+  var ents []string
+  for _, ent := range g.Ents {
+    line := fmt.Sprintf("ent %d %q at (%.2f, %.2f)", ent.Id, ent.Name, ent.X, ent.Y)
+    if ent.Stats != nil {
+      conditions := ent.Stats.ConditionNames()
+      sort.Strings(conditions)
+      line += fmt.Sprintf(" hp %d/%d ap %d/%d corpus %d ego %d conditions [%s]",
+        ent.Stats.HpCur(), ent.Stats.HpMax(), ent.Stats.ApCur(), ent.Stats.ApMax(),
+        ent.Stats.Corpus(), ent.Stats.Ego(), strings.Join(conditions, ", "))
+    }
+    if ent.Readied != nil {
+      line += fmt.Sprintf(" readied %d", ent.Readied.Index)
+    }
+    if ent.Active {
+      line += " active"
+    }
+    ents = append(ents, line)
+  }
+  sort.Strings(ents)
+  lines = append(lines, ents...)
+
+  if g.House != nil {
+    for fi, floor := range g.House.Floors {
+      for ri, room := range floor.Rooms {
+        for di, door := range room.Doors {
+          lines = append(lines, fmt.Sprintf("door %d/%d/%d opened %t", fi, ri, di, door.IsOpened()))
+        }
+      }
+    }
+  }
+
+  for _, wp := range g.Waypoints {
+    lines = append(lines, fmt.Sprintf("waypoint %q side %d at (%.2f, %.2f) radius %.2f", wp.Name, wp.Side, wp.X, wp.Y, wp.Radius))
+  }
+  return lines
+}
+
+func summaryChecksum(summary []string) []byte {
+  sum := sha1.Sum([]byte(strings.Join(summary, "\n")))
+  return sum[:]
+}
+
+// Returns the lines that are only in ours, prefixed with '-', followed by
+// the lines that are only in theirs, prefixed with '+'.
+func diffSummaries(ours, theirs []string) []string {
+  in_ours := make(map[string]bool)
+  for _, line := range ours {
+    in_ours[line] = true
+  }
+  in_theirs := make(map[string]bool)
+  for _, line := range theirs {
+    in_theirs[line] = true
+  }
+  var diff []string
+  for _, line := range ours {
+    if !in_theirs[line] {
+      diff = append(diff, "- "+line)
+    }
+  }
+  for _, line := range theirs {
+    if !in_ours[line] {
+      diff = append(diff, "+ "+line)
+    }
+  }
+  return diff
+}
+
+// Checks that the game is in the state that the other player said it would
+// be in after turn index of game.  Returns false and logs what differs if it
+// isn't.  Turns that were uploaded without a checksum always pass.
+func (g *Game) checkSync(game *mrgnet.Game, index int) bool {
+  if index < 0 || index >= len(game.Checksums) || game.Checksums[index] == nil {
+    return true
+  }
+  summary := g.stateSummary()
+  if bytes.Equal(summaryChecksum(summary), game.Checksums[index]) {
+    return true
+  }
+  base.Error().Printf("Desync detected after turn %d of game %s", index, g.net.key)
+  var theirs []string
+  if index < len(game.Summaries) && game.Summaries[index] != nil {
+    theirs = strings.Split(string(game.Summaries[index]), "\n")
+  }
+  for _, line := range diffSummaries(summary, theirs) {
+    base.Error().Printf("Desync: %s", line)
+  }
+  return false
+}
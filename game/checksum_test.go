@@ -0,0 +1,67 @@
+package game_test
+
+import (
+  "bytes"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+func ChecksumSpec(c gospec.Context) {
+  var a, b game.Game
+  a.SeedRand(1234)
+  b.SeedRand(1234)
+  a.Ents = []*game.Entity{
+    game.MakeBareEntity(1, "Teen", 2, 3),
+    game.MakeBareEntity(2, "Ghost", 5, 5),
+  }
+  b.Ents = []*game.Entity{
+    game.MakeBareEntity(2, "Ghost", 5, 5),
+    game.MakeBareEntity(1, "Teen", 2, 3),
+  }
+
+  c.Specify("Games in the same state have the same summary.", func() {
+    c.Expect(a.StateSummary(), ContainsInOrder, b.StateSummary())
+    c.Expect(bytes.Equal(game.SummaryChecksum(a.StateSummary()), game.SummaryChecksum(b.StateSummary())), Equals, true)
+    c.Expect(len(game.DiffSummaries(a.StateSummary(), b.StateSummary())), Equals, 0)
+  })
+
+  c.Specify("Games in different states have different summaries.", func() {
+    b.Ents[1].X = 4
+    c.Expect(bytes.Equal(game.SummaryChecksum(a.StateSummary()), game.SummaryChecksum(b.StateSummary())), Equals, false)
+    diff := game.DiffSummaries(a.StateSummary(), b.StateSummary())
+    c.Expect(diff, ContainsInOrder, []string{
+      `- ent 1 "Teen" at (2.00, 3.00)`,
+      `+ ent 1 "Teen" at (4.00, 3.00)`,
+    })
+  })
+
+  c.Specify("Games whose rngs are in different states have different summaries.", func() {
+    b.Rand.Int63()
+    diff := game.DiffSummaries(a.StateSummary(), b.StateSummary())
+    c.Expect(len(diff), Equals, 2)
+    c.Expect(diff[0][:len("- rand ")], Equals, "- rand ")
+    c.Expect(diff[1][:len("+ rand ")], Equals, "+ rand ")
+  })
+
+  c.Specify("Games are checked against the checksum of the turn they replayed.", func() {
+    b.Ents[1].X = 4
+    var net_game mrgnet.Game
+    net_game.Execs = [][]byte{[]byte("e0"), []byte("e1"), []byte("e2")}
+    net_game.Checksums = [][]byte{
+      game.SummaryChecksum(b.StateSummary()),
+      game.SummaryChecksum(a.StateSummary()),
+      game.SummaryChecksum(b.StateSummary()),
+    }
+    c.Expect(a.CheckSync(&net_game, 1), Equals, true)
+    c.Expect(a.CheckSync(&net_game, 0), Equals, false)
+    c.Expect(a.CheckSync(&net_game, 2), Equals, false)
+  })
+
+  c.Specify("Lines only in ours come before lines only in theirs.", func() {
+    ours := []string{"same", "ours 1", "ours 2"}
+    theirs := []string{"theirs", "same"}
+    c.Expect(game.DiffSummaries(ours, theirs), ContainsInOrder, []string{"- ours 1", "- ours 2", "+ theirs"})
+  })
+}
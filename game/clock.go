@@ -0,0 +1,77 @@
+package game
+
+// A Clock decides how much game time passes each time the game thinks.
+// Everything that moves or animates in a game is advanced by the time the
+// Clock returns, so a game run with a Clock that ignores the real time plays
+// out the same way no matter how fast or slow the machine running it is.
+type Clock interface {
+  // Given dt, the real time in milliseconds that has passed since the last
+  // time the game thought, returns the game time in milliseconds that
+  // should pass.
+  Elapsed(dt int64) int64
+}
+
+// The default Clock, game time passes exactly as fast as real time.
+type RealClock struct{}
+
+func (RealClock) Elapsed(dt int64) int64 {
+  return dt
+}
+
+// A Clock that advances the game by Step milliseconds every time it thinks,
+// regardless of how much real time has passed.
+type StepClock struct {
+  Step int64
+}
+
+func (c StepClock) Elapsed(dt int64) int64 {
+  return c.Step
+}
+
+// The logical step that the game is advanced by while fast-forwarding, and
+// the most game time that a single Think will fast-forward through, so that
+// an animation that never finishes can't hang the game.
+const fast_forward_step = 10
+const fast_forward_limit = 60 * 1000
+
+// Sets the Clock that decides how much time passes every time g thinks, nil
+// means RealClock.
+func (g *Game) SetClock(c Clock) {
+  g.clock = c
+}
+
+// If fast-forwarding is on then any time g thinks while an action is running
+// or an ent is animating it keeps advancing the game in logical steps until
+// the action has completed and every ent is idle.  Nothing that depends on
+// the script is done ahead of time, so the script still sees every action,
+// one at a time, as it would in real time.
+func (g *Game) SetFastForward(fast_forward bool) {
+  g.fast_forward = fast_forward
+}
+
+// Returns true if an action is running or any of the ents that take turns
+// are still animating.
+func (g *Game) animating() bool {
+  if g.Action_state == doingAction {
+    return true
+  }
+  for _, ent := range g.Ents {
+    if ent.Side() != SideHaunt && ent.Side() != SideExplorers {
+      continue
+    }
+    state := ent.Sprite().AnimState()
+    if state != "ready" && state != "killed" {
+      return true
+    }
+    if !ent.Sprite().Idle() {
+      return true
+    }
+  }
+  return false
+}
+
+func (g *Game) fastForward() {
+  for t := int64(0); t < fast_forward_limit && g.animating(); t += fast_forward_step {
+    g.think(fast_forward_step)
+  }
+}
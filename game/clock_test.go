@@ -0,0 +1,50 @@
+package game_test
+
+import (
+  "bytes"
+  "encoding/gob"
+  "time"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  _ "github.com/MobRulesGames/haunts/game/ai"
+)
+
+// Plays data_test/scripts/ai.lua, seeded with seed and fast-forwarded, until
+// the Ais have taken turns turns, and returns the gobbed game.
+func playFastForward(seed int64, turns int) ([]byte, error) {
+  h := game.MakeHeadlessGame("ai.lua", nil, nil, game.AutoInput{}, seed)
+  defer h.Close()
+  h.SetClock(game.StepClock{Step: 10})
+  h.SetFastForward(true)
+  if err := h.RunUntil(turns, 10*time.Second); err != nil {
+    return nil, err
+  }
+  buf := bytes.NewBuffer(nil)
+  if err := gob.NewEncoder(buf).Encode(h.Game()); err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
+
+func ClockSpec(c gospec.Context) {
+  c.Specify("RealClock passes real time through.", func() {
+    var clock game.RealClock
+    c.Expect(clock.Elapsed(16), Equals, int64(16))
+    c.Expect(clock.Elapsed(1000), Equals, int64(1000))
+  })
+  c.Specify("StepClock ignores real time.", func() {
+    clock := game.StepClock{Step: 10}
+    c.Expect(clock.Elapsed(16), Equals, int64(10))
+    c.Expect(clock.Elapsed(0), Equals, int64(10))
+  })
+  c.Specify("Fast-forwarded games seeded the same way end up in the same state.", func() {
+    loadTestRegistries()
+    a, err := playFastForward(1234, 4)
+    c.Assume(err, Equals, nil)
+    b, err := playFastForward(1234, 4)
+    c.Assume(err, Equals, nil)
+    c.Expect(len(a) > 0, Equals, true)
+    c.Expect(bytes.Equal(a, b), Equals, true)
+  })
+}
@@ -4,13 +4,157 @@ import (
   "github.com/MobRulesGames/haunts/game/status"
 )
 
-func (g *Game) DoAttack(attacker, defender *Entity, strength int, kind status.Kind) bool {
+// What is rolled to hit if an attack doesn't say otherwise.
+var default_attack_roll = Dice{Num: 1, Sides: 10}
+
+// Everything about an attack that decides whether it hits and how much
+// damage it does.
+type AttackSpec struct {
+  Strength int
+  Kind     status.Kind
+
+  // Rolled to hit, 1d10 if it is the zero Dice.
+  Roll Dice
+
+  // Rolled for damage if the attack hits.
+  Damage Dice
+
+  // If not zero, a roll to hit of at least Crit_on is a critical hit, which
+  // always hits and does Crit_damage on top of Damage.  A roll of at most
+  // Fumble_on is a fumble, which always misses.
+  Crit_on     int
+  Crit_damage Dice
+  Fumble_on   int
+}
+
+func (spec AttackSpec) roll() Dice {
+  if spec.Roll == (Dice{}) {
+    return default_attack_roll
+  }
+  return spec.Roll
+}
+
+// How an attack made with ResolveAttack turned out.
+type AttackResult struct {
+  // What was rolled to hit.
+  Roll int
+
+  Hit    bool
+  Crit   bool
+  Fumble bool
+
+  // Damage the attack does, before any of the defender's conditions modify
+  // it.  Zero if the attack missed.
+  Damage int
+}
+
+// Returns the lowest roll to hit that lets attacker hit defender.
+func minHitRoll(attacker, defender *Entity, strength int, kind status.Kind) int {
   // get attacker's bonus for using the specified kind of attack
   // get defender's bonus for defending against the specified kind of attack
   // get the defender's current ego/corpus
-  // successful attack = strength + attack bonus + 1d10 >= defense bonus + ego/corpus
+  // get the defender's cover from the attacker
+  // successful attack = strength + attack bonus + roll >= defense bonus + ego/corpus + cover
   attack := attacker.Stats.AttackBonusWith(kind)
-  defense := defender.Stats.DefenseVs(kind)
-  roll := int(g.Rand.Int63()%10) + 1
-  return strength+attack+roll >= defense
+  defense := defender.Stats.DefenseVs(kind) + attacker.Game().Cover(attacker, defender)
+  return defense - strength - attack
+}
+
+// Rolls an attack by attacker against defender.  Everything random comes
+// from g.Rand, and the damage is only rolled if the attack hits, so an
+// attack with a 1d10 roll and flat damage draws from g.Rand exactly like
+// DoAttack always has.
+func (g *Game) ResolveAttack(attacker, defender *Entity, spec AttackSpec) AttackResult {
+  var res AttackResult
+  res.Roll = spec.roll().Roll(g.Rand)
+  switch {
+  case spec.Fumble_on != 0 && res.Roll <= spec.Fumble_on:
+    res.Fumble = true
+  case spec.Crit_on != 0 && res.Roll >= spec.Crit_on:
+    res.Crit = true
+    res.Hit = true
+  default:
+    res.Hit = res.Roll >= minHitRoll(attacker, defender, spec.Strength, spec.Kind)
+  }
+  if res.Hit {
+    res.Damage = spec.Damage.Roll(g.Rand)
+    if res.Crit {
+      res.Damage += spec.Crit_damage.Roll(g.Rand)
+    }
+  }
+  return res
+}
+
+func (g *Game) DoAttack(attacker, defender *Entity, strength int, kind status.Kind) bool {
+  return g.ResolveAttack(attacker, defender, AttackSpec{Strength: strength, Kind: kind}).Hit
+}
+
+// The odds of an attack resolved by ResolveAttack, taking into account every
+// condition that the attacker and defender have right now.
+type AttackOdds struct {
+  // Chance that the attack hits, from 0 to 1, this includes critical hits.
+  Hit float64
+
+  // Chance that the attack is a critical hit.
+  Crit float64
+
+  // The defender's cover from the attacker, already taken into account in
+  // Hit.
+  Cover int
+
+  // Hp the defender can expect to lose, averaged over every way the attack
+  // could turn out.
+  Damage float64
+}
+
+// Returns the hp that defender can expect to lose if it takes damage rolled
+// from dice.
+func expectedHpLost(defender *Entity, kind status.Kind, dice ...Dice) float64 {
+  low := 0
+  for _, d := range dice {
+    low += d.Min()
+  }
+  lost := 0.0
+  for i, chance := range sumChances(dice...) {
+    dmg := defender.Stats.ModifiedDamage(status.Damage{Dynamic: status.Dynamic{Hp: -(low + i)}, Kind: kind})
+    if dmg.Dynamic.Hp < 0 {
+      lost += chance * float64(-dmg.Dynamic.Hp)
+    }
+  }
+  return lost
+}
+
+// Returns the odds of an attack made by attacker against defender.
+func CalcAttackOdds(attacker, defender *Entity, spec AttackSpec) AttackOdds {
+  var odds AttackOdds
+  if attacker.Stats == nil || defender.Stats == nil {
+    return odds
+  }
+  odds.Cover = attacker.Game().Cover(attacker, defender)
+  need := minHitRoll(attacker, defender, spec.Strength, spec.Kind)
+  roll := spec.roll()
+  for i, chance := range roll.Chances() {
+    r := roll.Min() + i
+    switch {
+    case spec.Fumble_on != 0 && r <= spec.Fumble_on:
+    case spec.Crit_on != 0 && r >= spec.Crit_on:
+      odds.Crit += chance
+    case r >= need:
+      odds.Hit += chance
+    }
+  }
+  odds.Damage = odds.Hit * expectedHpLost(defender, spec.Kind, spec.Damage)
+  if odds.Crit > 0 {
+    odds.Damage += odds.Crit * expectedHpLost(defender, spec.Kind, spec.Damage, spec.Crit_damage)
+  }
+  odds.Hit += odds.Crit
+  return odds
+}
+
+// Actions that attack a single ent, or that can hit one, implement this so
+// that the odds can be shown to the player while they pick a target.
+type AttackPreviewer interface {
+  // Returns the odds of the action against target as it is currently being
+  // targeted, ok is false if target wouldn't be attacked.
+  PreviewAttack(target *Entity) (odds AttackOdds, ok bool)
 }
@@ -0,0 +1,77 @@
+package game_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/status"
+)
+
+func makeStatsEnt(b status.Base) *game.Entity {
+  stats := status.MakeInst(b)
+  stats.OnBegin()
+  ent := &game.Entity{}
+  ent.Stats = &stats
+  return ent
+}
+
+func CombatSpec(c gospec.Context) {
+  attacker := makeStatsEnt(status.Base{Attack: 1})
+  defender := makeStatsEnt(status.Base{Corpus: 8, Hp_max: 5})
+
+  spec := func(strength int) game.AttackSpec {
+    return game.AttackSpec{Strength: strength, Kind: status.Fire, Damage: game.Dice{Bonus: 2}}
+  }
+
+  c.Specify("Attack odds follow the roll made by DoAttack.", func() {
+    // Needs a roll of at least 8 - 3 - 1 = 4, so 7 out of 10 rolls hit.
+    odds := game.CalcAttackOdds(attacker, defender, spec(3))
+    c.Expect(odds.Hit, IsWithin(1e-9), 0.7)
+    c.Expect(odds.Damage, IsWithin(1e-9), 1.4)
+  })
+
+  c.Specify("Attack odds never go past certain hits or misses.", func() {
+    odds := game.CalcAttackOdds(attacker, defender, spec(20))
+    c.Expect(odds.Hit, IsWithin(1e-9), 1.0)
+    c.Expect(odds.Damage, IsWithin(1e-9), 2.0)
+    odds = game.CalcAttackOdds(attacker, defender, spec(-20))
+    c.Expect(odds.Hit, IsWithin(1e-9), 0.0)
+    c.Expect(odds.Damage, IsWithin(1e-9), 0.0)
+  })
+
+  c.Specify("Crits always hit and fumbles always miss.", func() {
+    s := spec(-20)
+    s.Crit_on = 10
+    s.Crit_damage = game.Dice{Bonus: 3}
+    odds := game.CalcAttackOdds(attacker, defender, s)
+    c.Expect(odds.Hit, IsWithin(1e-9), 0.1)
+    c.Expect(odds.Crit, IsWithin(1e-9), 0.1)
+    c.Expect(odds.Damage, IsWithin(1e-9), 0.5)
+    s = spec(20)
+    s.Fumble_on = 2
+    odds = game.CalcAttackOdds(attacker, defender, s)
+    c.Expect(odds.Hit, IsWithin(1e-9), 0.8)
+  })
+
+  c.Specify("Dice expressions parse and give the right odds.", func() {
+    d, err := game.ParseDice("2d6+1")
+    c.Assume(err, Equals, nil)
+    c.Expect(d, Equals, game.Dice{Num: 2, Sides: 6, Bonus: 1})
+    c.Expect(d.String(), Equals, "2d6+1")
+    c.Expect(d.Min(), Equals, 3)
+    c.Expect(d.Max(), Equals, 13)
+    chances := d.Chances()
+    c.Expect(len(chances), Equals, 11)
+    c.Expect(chances[5], IsWithin(1e-9), 6.0/36)
+    d, err = game.ParseDice("d10")
+    c.Expect(err, Equals, nil)
+    c.Expect(d, Equals, game.Dice{Num: 1, Sides: 10})
+    d, err = game.ParseDice("3")
+    c.Expect(err, Equals, nil)
+    c.Expect(d, Equals, game.Dice{Bonus: 3})
+    _, err = game.ParseDice("2d")
+    c.Expect(err, Not(Equals), nil)
+    _, err = game.ParseDice("3+1")
+    c.Expect(err, Not(Equals), nil)
+  })
+}
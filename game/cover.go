@@ -0,0 +1,63 @@
+package game
+
+import (
+  "github.com/MobRulesGames/haunts/house"
+)
+
+// Returns the bonus to defense that defender gets against an attack made by
+// attacker.  This is the best cover given by any piece of furniture, or any
+// door frame, that lies on the line between them, cover from several things
+// doesn't add up.  Entities that are next to each other are fighting in melee
+// and never get cover.
+func (g *Game) Cover(attacker, defender *Entity) int {
+  if g == nil || g.House == nil || len(g.House.Floors) == 0 {
+    return 0
+  }
+  ax, ay := attacker.Pos()
+  dx, dy := defender.Pos()
+  if rangedDist(ax, ay, dx, dy) <= 1 {
+    return 0
+  }
+  var line [][2]int
+  bresenham(ax, ay, dx, dy, &line)
+  floor := g.House.Floors[0]
+  cover := 0
+  var prev *house.Room
+  for i, p := range line {
+    room := roomAt(floor, p[0], p[1])
+    if room == nil {
+      prev = nil
+      continue
+    }
+    if prev != nil && prev != room {
+      door := doorBetween(room, prev, p[0], p[1], line[i-1][0], line[i-1][1])
+      if door != nil && door.Cover > cover {
+        cover = door.Cover
+      }
+    }
+    prev = room
+    if i == 0 || i == len(line)-1 {
+      continue
+    }
+    furn := furnitureAt(room, p[0]-room.X, p[1]-room.Y)
+    if furn != nil && furn.Cover > cover {
+      cover = furn.Cover
+    }
+  }
+  return cover
+}
+
+func rangedDist(x, y, x2, y2 int) int {
+  dx := x - x2
+  if dx < 0 {
+    dx = -dx
+  }
+  dy := y - y2
+  if dy < 0 {
+    dy = -dy
+  }
+  if dx > dy {
+    return dx
+  }
+  return dy
+}
@@ -0,0 +1,52 @@
+package game_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+)
+
+// The cover house in data_test is the cover room, with Test Crates (cover 1)
+// at (3, 2), (3, 6) and (5, 6) and a Test Barricade (cover 2) at (5, 2),
+// joined to the test room at (10, 0) by a Test Door (cover 1) at y = 4.
+func CoverSpec(c gospec.Context) {
+  loadTestRegistries()
+  g := game.MakeGameInHouse("cover")
+  cover := func(ax, ay, dx, dy int) int {
+    attacker := game.MakeBareEntity(1, "Attacker", float64(ax), float64(ay))
+    defender := game.MakeBareEntity(2, "Defender", float64(dx), float64(dy))
+    return g.Cover(attacker, defender)
+  }
+
+  c.Specify("Nothing between the attacker and the defender gives no cover.", func() {
+    c.Expect(cover(1, 8, 7, 8), Equals, 0)
+    c.Expect(cover(12, 2, 18, 8), Equals, 0)
+  })
+
+  c.Specify("Furniture between the attacker and the defender gives cover.", func() {
+    c.Expect(cover(1, 2, 4, 2), Equals, 1)
+    c.Expect(cover(4, 0, 4, 2), Equals, 0)
+    c.Expect(cover(5, 0, 5, 4), Equals, 2)
+  })
+
+  c.Specify("Attacks through a door frame give cover.", func() {
+    c.Expect(cover(7, 4, 13, 4), Equals, 1)
+    c.Expect(cover(13, 4, 7, 4), Equals, 1)
+  })
+
+  c.Specify("Cover is the best of everything in the way, not the sum.", func() {
+    c.Expect(cover(1, 6, 7, 6), Equals, 1)
+    c.Expect(cover(1, 2, 7, 2), Equals, 2)
+  })
+
+  c.Specify("Furniture in the attacker's or the defender's cell doesn't count.", func() {
+    c.Expect(cover(3, 2, 3, 5), Equals, 0)
+    c.Expect(cover(3, 8, 3, 6), Equals, 0)
+  })
+
+  c.Specify("Melee attacks ignore cover.", func() {
+    c.Expect(cover(9, 4, 10, 4), Equals, 0)
+    c.Expect(cover(2, 2, 3, 2), Equals, 0)
+    c.Expect(cover(4, 2, 5, 2), Equals, 0)
+  })
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// A Dice is a dice expression like "2d6+1", Num dice with Sides sides each,
+// added together along with Bonus.  A Dice with no dice is just its Bonus,
+// so "3" is a valid expression as well.
+type Dice struct {
+  Num, Sides, Bonus int
+}
+
+// Where the dice come from, a Game's Rand is one of these.
+type DiceSource interface {
+  Int63() int64
+}
+
+// Parses expressions like "2d6+1", "d10", "1d4-1" and "3".
+func ParseDice(expr string) (Dice, error) {
+  var d Dice
+  s := strings.ToLower(strings.Replace(expr, " ", "", -1))
+  if s == "" {
+    return d, fmt.Errorf("Empty dice expression.")
+  }
+  bad := fmt.Errorf("'%s' is not a valid dice expression.", expr)
+  pos := strings.IndexAny(s, "+-")
+  if pos == 0 {
+    // Only a constant can start with a sign.
+    pos = -1
+  }
+  if pos > 0 {
+    bonus, err := strconv.Atoi(s[pos:])
+    if err != nil {
+      return d, bad
+    }
+    d.Bonus = bonus
+    s = s[:pos]
+  }
+  dpos := strings.Index(s, "d")
+  if dpos == -1 {
+    if pos > 0 {
+      return d, bad
+    }
+    bonus, err := strconv.Atoi(s)
+    if err != nil {
+      return d, bad
+    }
+    d.Bonus = bonus
+    return d, nil
+  }
+  d.Num = 1
+  if dpos > 0 {
+    num, err := strconv.Atoi(s[:dpos])
+    if err != nil || num < 0 {
+      return d, bad
+    }
+    d.Num = num
+  }
+  sides, err := strconv.Atoi(s[dpos+1:])
+  if err != nil || sides < 1 {
+    return d, bad
+  }
+  d.Sides = sides
+  return d, nil
+}
+
+func (d Dice) String() string {
+  if d.Num == 0 || d.Sides == 0 {
+    return fmt.Sprintf("%d", d.Bonus)
+  }
+  switch {
+  case d.Bonus > 0:
+    return fmt.Sprintf("%dd%d+%d", d.Num, d.Sides, d.Bonus)
+  case d.Bonus < 0:
+    return fmt.Sprintf("%dd%d%d", d.Num, d.Sides, d.Bonus)
+  }
+  return fmt.Sprintf("%dd%d", d.Num, d.Sides)
+}
+
+func (d Dice) Min() int {
+  if d.Sides == 0 {
+    return d.Bonus
+  }
+  return d.Num + d.Bonus
+}
+
+func (d Dice) Max() int {
+  return d.Num*d.Sides + d.Bonus
+}
+
+// Rolls the dice, taking one number from src for each die.
+func (d Dice) Roll(src DiceSource) int {
+  total := d.Bonus
+  if d.Sides == 0 {
+    return total
+  }
+  for i := 0; i < d.Num; i++ {
+    total += int(src.Int63()%int64(d.Sides)) + 1
+  }
+  return total
+}
+
+// Returns the chance of rolling each total from Min() to Max(), chances[i]
+// is the chance of rolling Min() + i.
+func (d Dice) Chances() []float64 {
+  chances := []float64{1}
+  if d.Sides == 0 {
+    return chances
+  }
+  for i := 0; i < d.Num; i++ {
+    next := make([]float64, len(chances)+d.Sides-1)
+    for j, c := range chances {
+      for k := 0; k < d.Sides; k++ {
+        next[j+k] += c / float64(d.Sides)
+      }
+    }
+    chances = next
+  }
+  return chances
+}
+
+// Returns the chance of rolling each total from the sum of the Min()s to the
+// sum of the Max()s when all of dice are rolled and added together.
+func sumChances(dice ...Dice) []float64 {
+  chances := []float64{1}
+  for _, d := range dice {
+    dc := d.Chances()
+    next := make([]float64, len(chances)+len(dc)-1)
+    for i, a := range chances {
+      for j, b := range dc {
+        next[i+j] += a * b
+      }
+    }
+    chances = next
+  }
+  return chances
+}
@@ -420,6 +420,10 @@ type EntityInst struct {
   // For inanimate objects - some of them need to be activated so we know when
   // the players can interact with them.
   Active bool
+
+  // The action this entity has readied, if any, it is only readied until
+  // the entity's side gets its next turn.
+  Readied *ReadiedAction
 }
 type aiStatus int
 
@@ -687,6 +691,7 @@ func (e *Entity) SetGear(gear_name string) bool {
 }
 
 func (e *Entity) OnRound() {
+  e.Readied = nil
   if e.Stats != nil {
     e.Stats.OnRound()
     if e.Stats.HpCur() <= 0 {
@@ -0,0 +1,86 @@
+package game
+
+import (
+  "bytes"
+  "encoding/gob"
+  "github.com/MobRulesGames/haunts/house"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "os"
+  "time"
+)
+
+// Gives the specs, which are in game_test, access to the parts of the package
+// that they check but that nothing else needs.
+
+func (g *Game) StateSummary() []string {
+  return g.stateSummary()
+}
+
+var DiffSummaries = diffSummaries
+var SummaryChecksum = summaryChecksum
+
+func (g *Game) CheckSync(game *mrgnet.Game, index int) bool {
+  return g.checkSync(game, index)
+}
+
+// Makes an entity that isn't loaded from any definition, it has a name and a
+// position and nothing else.
+func MakeBareEntity(id EntityId, name string, x, y float64) *Entity {
+  var ent Entity
+  ent.entityDef = &entityDef{Name: name}
+  ent.Id = id
+  ent.X, ent.Y = x, y
+  return &ent
+}
+
+// Makes a game in the house called name, which has to have been loaded
+// already.
+func MakeGameInHouse(name string) *Game {
+  return makeGame(house.MakeHouseFromName(name))
+}
+
+func EncodeGameState(g *Game, store []byte) (string, error) {
+  return encodeGameState(g, store)
+}
+
+func DecodeGameState(state string) (*Game, []byte, error) {
+  var g *Game
+  store, err := decodeGameState(state, &g)
+  return g, store, err
+}
+
+var ReadSaveHeader = readSaveHeader
+var UpgradeSave = upgradeSave
+
+// Returns body with a header claiming that it is a save of version.
+func AddSaveHeader(version int, body []byte) []byte {
+  buf := bytes.NewBuffer(nil)
+  gob.NewEncoder(buf).Encode(saveHeader{Magic: save_magic, Version: version})
+  buf.Write(body)
+  return buf.Bytes()
+}
+
+// Runs f as if there were no migration from version from.
+func WithoutSaveMigration(from int, f func()) {
+  m := save_migrations[from]
+  delete(save_migrations, from)
+  defer func() {
+    save_migrations[from] = m
+  }()
+  f()
+}
+
+// Writes p to the slot called name, the same way saveToSlot does but without
+// a game, as if it had been saved at t.
+func WriteSaveSlot(p *Player, name string, autosave bool, t time.Time) error {
+  info := SaveInfo{
+    Name:     name,
+    Script:   p.Script_path,
+    Time:     t,
+    Autosave: autosave,
+  }
+  if err := os.MkdirAll(savesDir(), 0755); err != nil {
+    return err
+  }
+  return writeSave(saveSlotPath(name, autosave), info, p)
+}
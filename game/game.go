@@ -6,7 +6,6 @@ import (
   "github.com/MobRulesGames/haunts/base"
   "github.com/MobRulesGames/haunts/house"
   "github.com/MobRulesGames/haunts/mrgnet"
-  "math/rand"
   "sort"
 )
 
@@ -21,8 +20,24 @@ type GamePanel struct {
 
   complete gui.Widget
 
+  // Only shown in online games.
+  chat *ChatPanel
+
+  // Only shown when watching a replay.
+  replay *ReplayControls
+
   script *gameScript
   game   *Game
+
+  // Only set when running headless, answers for the player anything that
+  // the script would ask through the ui.
+  input HeadlessInput
+
+  // If not zero, games made by the script are seeded with this.
+  seed int64
+
+  // Set once the script calls EndGame in a headless game.
+  over bool
 }
 
 func MakeGamePanel(script string, p *Player, data map[string]string, game_key mrgnet.GameKey) *GamePanel {
@@ -174,6 +189,11 @@ func (gp *GamePanel) Respond(ui *gui.Gui, group gui.EventGroup) bool {
     }
   }
 
+  if gp.game.net.spectator {
+    // Spectators can hover over things but can't select them.
+    return false
+  }
+
   if found, event := group.FindEvent(gin.Escape); found && event.Type == gin.Press {
     if gp.game.selected_ent != nil {
       switch gp.game.Action_state {
@@ -280,7 +300,7 @@ func spawnEnts(g *Game, ents []*Entity, spawns []*house.SpawnPoint) {
     sort.Sort(orderEntsBigToSmall(ents))
     //slightly shuffle the ents
     for i := range ents {
-      j := i + rand.Intn(5) - 2
+      j := i + int(g.Rand.Int63()%5) - 2
       if j >= 0 && j < len(ents) {
         ents[i], ents[j] = ents[j], ents[i]
       }
@@ -310,8 +330,8 @@ func spawnEnts(g *Game, ents []*Entity, spawns []*house.SpawnPoint) {
     base.Warn().Printf("Only able to place %d out of %d objects", len(places), len(spawns))
   }
   for _, place := range places {
-    place.ent.X = float64(place.spawn.X + rand.Intn(place.spawn.Dx-place.ent.Dx+1))
-    place.ent.Y = float64(place.spawn.Y + rand.Intn(place.spawn.Dy-place.ent.Dy+1))
+    place.ent.X = float64(place.spawn.X + int(g.Rand.Int63()%int64(place.spawn.Dx-place.ent.Dx+1)))
+    place.ent.Y = float64(place.spawn.Y + int(g.Rand.Int63()%int64(place.spawn.Dy-place.ent.Dy+1)))
     g.viewer.AddDrawable(place.ent)
     g.Ents = append(g.Ents, place.ent)
     base.Log().Printf("Using object '%s' at (%.0f, %.0f)", place.ent.Name, place.ent.X, place.ent.Y)
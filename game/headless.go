@@ -0,0 +1,264 @@
+package game
+
+import (
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game/status"
+  "github.com/MobRulesGames/haunts/house"
+  "path/filepath"
+  "regexp"
+  "runtime"
+  "time"
+)
+
+// Loads everything in datadir that games are made from.  Anything that uses
+// this needs to import haunts/game/actions and haunts/game/ai as well, since
+// those register themselves.
+func LoadAllRegistries(datadir string) {
+  house.LoadAllFurnitureInDir(filepath.Join(datadir, "furniture"))
+  house.LoadAllWallTexturesInDir(filepath.Join(datadir, "textures"))
+  house.LoadAllRoomsInDir(filepath.Join(datadir, "rooms"))
+  house.LoadAllDoorsInDir(filepath.Join(datadir, "doors"))
+  house.LoadAllHousesInDir(filepath.Join(datadir, "houses"))
+  LoadAllGearInDir(filepath.Join(datadir, "gear"))
+  RegisterActions()
+  status.RegisterAllConditions()
+  LoadAllEntities()
+}
+
+// Gets everything ready to run games headless from the data in datadir, this
+// is all that tools that run games without a window need to do first.
+func SetupHeadless(datadir string) error {
+  abs, err := filepath.Abs(datadir)
+  if err != nil {
+    return err
+  }
+  base.SetHeadless(true)
+  base.SetDatadir(abs)
+  if err := house.SetDatadir(abs); err != nil {
+    return err
+  }
+  base.InitShaders()
+  LoadAllRegistries(abs)
+  return nil
+}
+
+// Stands in for the player when a game is run headless.  Any time a script
+// would wait on the player to do something through the ui it asks the
+// HeadlessInput instead.
+type HeadlessInput interface {
+  // Called instead of showing the dialog box at path, returns the choices
+  // that were made in it.
+  Dialog(path string, args map[string]string) []string
+
+  // Called for ChooserFromFile, PickFromN and SelectHouse, returns the ids
+  // of the chosen options.  name is the file the options came from, or the
+  // name of the script function if they didn't come from a file.
+  Choose(name string, options []string, min, max int) []string
+
+  // Called for PlaceEntities, should place ents in the spawn points that
+  // match pattern and return the ones that it placed.
+  Place(g *Game, names []string, costs []int, min, max int, pattern string) []*Entity
+
+  // Called when the script binds target, one of "denizen" or "intruder", to
+  // the player.  Returns the path of an Ai, relative to the ais directory,
+  // to bind instead, or an empty string to leave target to the player.
+  HumanAi(target string) string
+}
+
+// Notified about every action that a game executes, this is how tools that
+// collect statistics on headless games find out what happened.
+type ActionWatcher interface {
+  // Called right before ent starts executing action.
+  ActionStarted(g *Game, ent *Entity, action Action)
+
+  // Called right after the action that most recently started completes.
+  ActionCompleted(g *Game, action Action)
+}
+
+// A HeadlessInput that skips through dialogs, takes the first options it is
+// offered and places ents in the first spots it finds.
+type AutoInput struct{}
+
+func (AutoInput) Dialog(path string, args map[string]string) []string {
+  return nil
+}
+
+func (AutoInput) Choose(name string, options []string, min, max int) []string {
+  if min <= 0 && max > 0 {
+    min = 1
+  }
+  if min > len(options) {
+    min = len(options)
+  }
+  return options[0:min]
+}
+
+func (AutoInput) HumanAi(target string) string {
+  return ""
+}
+
+func (AutoInput) Place(g *Game, names []string, costs []int, min, max int, pattern string) []*Entity {
+  var ents []*Entity
+  points := max
+  for i, name := range names {
+    for points >= costs[i] {
+      ent := g.PlaceEntityInSpawns(name, pattern)
+      if ent == nil {
+        break
+      }
+      ents = append(ents, ent)
+      points -= costs[i]
+      if costs[i] <= 0 {
+        break
+      }
+    }
+  }
+  return ents
+}
+
+// Places a new entity in the first free spot it finds in the spawn points
+// that match pattern, following the same rules as the entity placer.
+// Returns the entity, or nil if there was no room for it.
+func (g *Game) PlaceEntityInSpawns(name, pattern string) *Entity {
+  re, err := regexp.Compile(pattern)
+  if err != nil {
+    base.Error().Printf("Failed to compile regexp: '%s': %v", pattern, err)
+    return nil
+  }
+  ent := MakeEntity(name, g)
+  for _, spawn := range g.House.Floors[0].Spawns {
+    if !re.MatchString(spawn.Name) {
+      continue
+    }
+    sx, sy := spawn.Pos()
+    sdx, sdy := spawn.Dims()
+    for x := sx; x < sx+sdx; x++ {
+      for y := sy; y < sy+sdy; y++ {
+        ent.X, ent.Y = float64(x), float64(y)
+        g.new_ent = ent
+        if g.placeEntity(pattern) {
+          return ent
+        }
+      }
+    }
+  }
+  g.new_ent = nil
+  return nil
+}
+
+// A HeadlessGame runs a level script with no window and no OpenGL context,
+// base.SetHeadless(true) must have been called before anything was loaded.
+// Nothing happens unless Think is called, and every call to Think advances
+// the game by exactly as much time as it is told to, so a game can be run
+// as fast as the cpu allows.
+//
+// Sides that are bound to an Ai play themselves, a side that is bound to
+// "human" has to be played through Game() and ended with EndTurn().
+type HeadlessGame struct {
+  gp           *GamePanel
+  watcher      ActionWatcher
+  clock        Clock
+  fast_forward bool
+}
+
+// If seed is not zero the game is seeded with it as soon as the script loads
+// a house, so that games made with the same seed play out the same way.
+func MakeHeadlessGame(script string, p *Player, data map[string]string, input HeadlessInput, seed int64) *HeadlessGame {
+  if !base.Headless() {
+    base.Warn().Printf("Making a headless game without base.SetHeadless(true).")
+  }
+  var gp GamePanel
+  gp.input = input
+  gp.seed = seed
+  if p == nil {
+    p = &Player{}
+  }
+  if script == "" {
+    script = p.Script_path
+  }
+  startGameScript(&gp, script, p, data, "")
+  return &HeadlessGame{gp: &gp}
+}
+
+// Lets the script run anything it has waiting and then advances the game
+// by dt milliseconds.
+func (h *HeadlessGame) Think(dt int64) {
+  h.gp.scriptThinkOnce()
+  if !h.gp.Active() {
+    return
+  }
+  // The script can replace the game at any time, so this has to be checked
+  // every time.
+  h.gp.game.watcher = h.watcher
+  h.gp.game.clock = h.clock
+  h.gp.game.fast_forward = h.fast_forward
+  h.gp.game.Think(dt)
+}
+
+// How far RunUntil advances the game every time it thinks.
+const run_until_dt = 10
+
+// Thinks until the game has got past turn turns, the script and the Ais run
+// in their own go routines so they are given a chance to catch up after
+// every Think.  Returns an error if that takes longer than timeout, or if
+// the game ends first.
+func (h *HeadlessGame) RunUntil(turns int, timeout time.Duration) error {
+  start := time.Now()
+  for h.Game() == nil || h.Game().Turn <= turns {
+    if h.Over() {
+      return fmt.Errorf("The game ended before turn %d.", turns+1)
+    }
+    if time.Since(start) > timeout {
+      return fmt.Errorf("Timed out waiting for turn %d.", turns+1)
+    }
+    h.Think(run_until_dt)
+    runtime.Gosched()
+  }
+  return nil
+}
+
+// Sets w to be notified about every action that the game executes from now
+// on.
+func (h *HeadlessGame) Watch(w ActionWatcher) {
+  h.watcher = w
+}
+
+// Sets the Clock used by every game that the script makes, see
+// Game.SetClock.
+func (h *HeadlessGame) SetClock(c Clock) {
+  h.clock = c
+}
+
+// Turns fast-forwarding on or off for every game that the script makes, see
+// Game.SetFastForward.
+func (h *HeadlessGame) SetFastForward(fast_forward bool) {
+  h.fast_forward = fast_forward
+}
+
+// Returns the game, or nil if the script hasn't loaded a house yet.  The
+// game should only be touched between calls to Think.
+func (h *HeadlessGame) Game() *Game {
+  return h.gp.game
+}
+
+// Ends the turn of a side bound to "human", the same as pressing the end
+// turn button.
+func (h *HeadlessGame) EndTurn() {
+  if h.gp.game != nil {
+    h.gp.game.player_inactive = true
+  }
+}
+
+// Returns true once the script has called EndGame.
+func (h *HeadlessGame) Over() bool {
+  return h.gp.over
+}
+
+// Stops the game's Ais and releases its ents, the HeadlessGame can't be used
+// after this.
+func (h *HeadlessGame) Close() {
+  if h.gp.game != nil {
+    releaseGame(h.gp.game)
+  }
+}
@@ -0,0 +1,18 @@
+package game_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+)
+
+func HeadlessSpec(c gospec.Context) {
+  c.Specify("AutoInput takes the first options it is offered.", func() {
+    var input game.AutoInput
+    options := []string{"Humans", "Intruders", "Denizens"}
+    c.Expect(input.Choose("side.json", options, 1, 1), ContainsExactly, []string{"Humans"})
+    c.Expect(input.Choose("PickFromN", options, 2, 3), ContainsInOrder, []string{"Humans", "Intruders"})
+    c.Expect(len(input.Choose("PickFromN", options, 0, 0)), Equals, 0)
+    c.Expect(len(input.Choose("PickFromN", nil, 1, 1)), Equals, 0)
+  })
+}
@@ -2,6 +2,8 @@ package game
 
 import (
   "bytes"
+  crand "crypto/rand"
+  "encoding/binary"
   "encoding/gob"
   "errors"
   gl "github.com/MobRulesGames/gogl/gl21"
@@ -59,7 +61,11 @@ const (
 
 type sideLosData struct {
   mode LosMode
-  tex  *house.LosTexture
+
+  // The rooms that were passed to SetLosMode, if mode is LosModeRooms.
+  rooms []*house.Room
+
+  tex *house.LosTexture
 }
 
 type waypoint struct {
@@ -132,6 +138,10 @@ type gameDataTransient struct {
     key  mrgnet.GameKey
     game *mrgnet.Game
     side Side
+
+    // If true we're only watching this game, side is the side whose
+    // visibility we follow.
+    spectator bool
   }
 }
 
@@ -158,6 +168,14 @@ type gameDataPrivate struct {
   // Hacky - but gives us a way to prevent selecting ents and whatnot while
   // any kind of modal dialog box is up.
   modal bool
+
+  // Only set for headless games that someone is collecting statistics on.
+  watcher ActionWatcher
+
+  // Decides how much time passes on each Think, nil is the same as
+  // RealClock.  See SetClock and SetFastForward.
+  clock        Clock
+  fast_forward bool
 }
 type spawnLos struct {
   Pattern string
@@ -195,9 +213,18 @@ type gameDataGobbable struct {
   Turn int
 
   // PRNG, need it here so that we serialize it along with everything
-  // else so that replays work properly.
+  // else so that replays work properly.  Anything random that affects the
+  // outcome of the game must come from here.
   Rand *cmwc.Cmwc
 
+  // Separate PRNG for the Ais.  The Ais only run on one client, and their
+  // decisions reach the other client as execs, so if they drew from Rand
+  // the two clients would end up with different Rands.
+  Ai_rand *cmwc.Cmwc
+
+  // Both of the PRNGs were seeded from this, see SeedRand.
+  Seed int64
+
   // Waypoints, used for signaling things to the player on the map
   Waypoints []waypoint
 
@@ -228,6 +255,14 @@ type gameDataGobbable struct {
 
   current_exec   ActionExec
   current_action Action
+
+  // The exec of the action that is currently executing, current_exec is
+  // cleared as soon as the action starts but interrupts need to get at it.
+  action_exec ActionExec
+
+  // Interrupts that have gone off and are waiting for the current action to
+  // finish.
+  interrupts []interrupt
 }
 
 type Game struct {
@@ -266,6 +301,12 @@ func (g *Game) GobDecode(data []byte) error {
     base.GetObject("entities", ent)
   }
 
+  // Games saved before the Ais had their own PRNG need one.
+  if g.Ai_rand == nil {
+    g.Ai_rand = cmwc.MakeCmwc(4285415527, 3)
+    g.Ai_rand.Seed(g.Seed ^ ai_seed_salt)
+  }
+
   g.setup()
   for _, ent := range g.Ents {
     ent.Load(g)
@@ -326,6 +367,31 @@ func (g *Game) GobEncode() ([]byte, error) {
   return buf.Bytes(), nil
 }
 
+// Used to derive the Ai's seed from the game's seed so that the two streams
+// aren't the same.
+const ai_seed_salt = 0x5ca1ab1e
+
+// Resets both of the game's PRNGs so that they produce the same sequence as
+// any other game seeded with the same value.
+func (g *Game) SeedRand(seed int64) {
+  g.Seed = seed
+  g.Rand = cmwc.MakeCmwc(4285415527, 3)
+  g.Rand.Seed(seed)
+  g.Ai_rand = cmwc.MakeCmwc(4285415527, 3)
+  g.Ai_rand.Seed(seed ^ ai_seed_salt)
+}
+
+// Returns a seed for a new game.
+func randomSeed() int64 {
+  var seed int64
+  err := binary.Read(crand.Reader, binary.LittleEndian, &seed)
+  if err != nil {
+    base.Warn().Printf("Unable to get a random seed, using the time instead: %v", err)
+    seed = time.Now().UnixNano()
+  }
+  return seed
+}
+
 func (g *Game) EntityById(id EntityId) *Entity {
   for i := range g.Ents {
     if g.Ents[i].Id == id {
@@ -440,6 +506,8 @@ func (g *Game) OnRound(do_scripts bool) {
     g.viewer.Los_tex.Remap()
   }
 
+  // Interrupts only last as long as the turn they went off in.
+  g.interrupts = nil
   for i := range g.Ents {
     if g.Ents[i].Side() == g.Side {
       g.Ents[i].OnRound()
@@ -572,6 +640,13 @@ func connected(r, r2 *house.Room, x, y, x2, y2 int) bool {
   if r == r2 {
     return true
   }
+  door := doorBetween(r, r2, x, y, x2, y2)
+  return door != nil && door.IsOpened()
+}
+
+// Returns the door on r that lies between x,y in r and x2,y2 in r2, or nil if
+// there isn't one.  Coordinates are given in floor coordinates.
+func doorBetween(r, r2 *house.Room, x, y, x2, y2 int) *house.Door {
   x -= r.X
   y -= r.Y
   x2 -= r2.X
@@ -588,7 +663,7 @@ func connected(r, r2 *house.Room, x, y, x2, y2 int) bool {
   } else {
     // This shouldn't happen, but in case it does we certainly shouldn't treat
     // it as an open door
-    return false
+    return nil
   }
   for _, door := range r.Doors {
     if door.Facing != facing {
@@ -607,10 +682,10 @@ func connected(r, r2 *house.Room, x, y, x2, y2 int) bool {
       pos = x
     }
     if pos >= door.Pos && pos < door.Pos+door.Width {
-      return door.IsOpened()
+      return door
     }
   }
-  return false
+  return nil
 }
 
 func (g *Game) IsCellOccupied(x, y int) bool {
@@ -813,8 +888,7 @@ func makeGame(h *house.HouseDef) *Game {
   g.House = h
   g.House.Normalize()
   g.viewer = house.MakeHouseViewer(g.House, 62)
-  g.Rand = cmwc.MakeCmwc(4285415527, 3)
-  g.Rand.SeedWithDevRand()
+  g.SeedRand(randomSeed())
 
   // This way an unset id will be invalid
   g.Entity_id = 1
@@ -839,6 +913,7 @@ func (g *Game) SetLosMode(side Side, mode LosMode, rooms []*house.Room) {
     return
   }
   data.mode = mode
+  data.rooms = rooms
   pix := data.tex.Pix()
 
   switch data.mode {
@@ -899,7 +974,19 @@ func (g *Game) SetLosMode(side Side, mode LosMode, rooms []*house.Room) {
   data.tex.Remap()
 }
 
+// Advances the game by however much time the game's Clock says has passed
+// since the last Think, dt is the real time that has passed in milliseconds.
 func (g *Game) Think(dt int64) {
+  if g.clock != nil {
+    dt = g.clock.Elapsed(dt)
+  }
+  g.think(dt)
+  if g.fast_forward {
+    g.fastForward()
+  }
+}
+
+func (g *Game) think(dt int64) {
   for _, ent := range g.Ents {
     if !g.all_ents_in_game[ent] {
       g.all_ents_in_game[ent] = true
@@ -996,6 +1083,7 @@ func (g *Game) Think(dt int64) {
         } else {
           base.Log().Printf("ScriptComm: change to turnStateAiAction")
           g.Turn_state = turnStateAiAction
+          g.startInterrupt()
         }
       }
     default:
@@ -1051,16 +1139,33 @@ func (g *Game) Think(dt int64) {
   // If there is an action that is currently executing we need to advance that
   // action.
   if g.Action_state == doingAction {
-    res := g.current_action.Maintain(dt, g, g.current_exec)
+    if g.current_exec != nil && g.watcher != nil {
+      g.watcher.ActionStarted(g, g.EntityById(g.current_exec.EntityId()), g.current_action)
+    }
+    if g.current_exec != nil {
+      g.startReadied(g.current_exec)
+    }
+    var res MaintenanceStatus
+    if exec, ok := g.current_exec.(*ReadyExec); ok {
+      g.doReady(exec)
+      res = Complete
+    } else {
+      res = g.current_action.Maintain(dt, g, g.current_exec)
+    }
     if g.current_exec != nil {
       base.Log().Printf("ScriptComm: sent action")
+      g.action_exec = g.current_exec
       g.current_exec = nil
     }
     switch res {
     case Complete:
       g.current_action.Cancel()
+      if g.watcher != nil {
+        g.watcher.ActionCompleted(g, g.current_action)
+      }
       g.viewer.RemoveFloorDrawable(g.current_action)
       g.current_action = nil
+      g.action_exec = nil
       g.Action_state = noAction
       if g.Turn_state != turnStateMainPhaseOver {
         g.Turn_state = turnStateScriptOnAction
@@ -1071,6 +1176,7 @@ func (g *Game) Think(dt int64) {
 
     case InProgress:
     case CheckForInterrupts:
+      g.checkInterrupts()
     }
   }
 
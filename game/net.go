@@ -0,0 +1,234 @@
+package game
+
+import (
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "time"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// Server settings specified on the command line.  Anything set here takes
+// precedence over the corresponding value in the store for the lifetime of
+// this process, but is never written to the store.
+var Net_flags struct {
+  // Base url of the server, e.g. "https://localhost:8080/"
+  Server_url string
+
+  // List of PEM files, separated by filepath.ListSeparator, containing
+  // additional certificates to trust.
+  Ca_certs string
+
+  Timeout time.Duration
+}
+
+// Store keys for the server settings.
+const (
+  store_server_url     = "server url"
+  store_server_ca      = "server ca certs"
+  store_server_timeout = "server timeout"
+  store_identity       = "identity"
+
+  // From before identities, this is only read to migrate old players.
+  store_netid = "netid"
+)
+
+// File in the datadir that the online menu backs up identities to.
+const identity_backup_file = "identity.txt"
+
+// Configures mrgnet from the store and Net_flags.  Should be called before
+// anything talks to the server, and again any time the settings change.
+func ConfigureNet() error {
+  if _, err := loadIdentity(); err != nil {
+    return err
+  }
+
+  var config mrgnet.Config
+  config.Host_url = base.GetStoreVal(store_server_url)
+  if Net_flags.Server_url != "" {
+    config.Host_url = Net_flags.Server_url
+  }
+
+  ca_certs := base.GetStoreVal(store_server_ca)
+  if Net_flags.Ca_certs != "" {
+    ca_certs = Net_flags.Ca_certs
+  }
+  for _, path := range filepath.SplitList(ca_certs) {
+    path = strings.TrimSpace(path)
+    if path == "" {
+      continue
+    }
+    if !filepath.IsAbs(path) {
+      path = filepath.Join(base.GetDataDir(), path)
+    }
+    config.Ca_certs = append(config.Ca_certs, path)
+  }
+
+  if timeout := base.GetStoreVal(store_server_timeout); timeout != "" {
+    d, err := time.ParseDuration(timeout)
+    if err != nil {
+      base.Warn().Printf("Ignoring invalid server timeout '%s': %v", timeout, err)
+    } else {
+      config.Timeout = d
+    }
+  }
+  if Net_flags.Timeout != 0 {
+    config.Timeout = Net_flags.Timeout
+  }
+
+  return mrgnet.Configure(config)
+}
+
+// Sets the server url in the store and reconfigures mrgnet to use it.  A url
+// specified on the command line still takes precedence.
+func SetServerUrl(url string) error {
+  old := base.GetStoreVal(store_server_url)
+  base.SetStoreVal(store_server_url, url)
+  err := ConfigureNet()
+  if err != nil {
+    base.SetStoreVal(store_server_url, old)
+  }
+  return err
+}
+
+// Returns a message suitable for showing to the player for an error returned
+// by mrgnet.DoAction.
+func netErrorMessage(err error) string {
+  switch e := err.(type) {
+  case *mrgnet.VersionError:
+    return e.Error()
+  case *mrgnet.ServerError:
+    return fmt.Sprintf("Server error: %s", e.Msg)
+  }
+  return fmt.Sprintf("Couldn't connect to server: %v", err)
+}
+
+// Blocks until the specified game has at least the specified number of
+// turns on the server, or is over, and returns the game without its playback
+// data.  The server holds on to each request until something changes so this
+// returns as soon as the turn is available.  If the server doesn't support
+// that we fall back to polling it.
+func waitForTurns(net_id mrgnet.NetId, key mrgnet.GameKey, turns int) (*mrgnet.Game, error) {
+  poll := false
+  for {
+    var game *mrgnet.Game
+    var err error
+    if poll {
+      var resp mrgnet.StatusResponse
+      err = mrgnet.DoAction("status", mrgnet.StatusRequest{Id: net_id, Game_key: key, Sizes_only: true}, &resp)
+      if err == nil && resp.Err != "" {
+        return nil, errors.New(resp.Err)
+      }
+      game = resp.Game
+    } else {
+      var resp mrgnet.WaitResponse
+      req := mrgnet.WaitRequest{Id: net_id, Game_key: key, Turns: turns, Timeout: mrgnet.Long_poll_duration}
+      err = mrgnet.DoLongPoll("wait", req, &resp, req.Timeout)
+      if serr, ok := err.(*mrgnet.ServerError); ok && serr.Unsupported() {
+        base.Warn().Printf("Server doesn't support waiting, polling instead: %v", err)
+        poll = true
+        continue
+      }
+      if err == nil && resp.Err != "" {
+        return nil, errors.New(resp.Err)
+      }
+      if err == nil && !resp.Ready {
+        // The request timed out on the server, just ask again.
+        continue
+      }
+      game = resp.Game
+    }
+    switch err.(type) {
+    case *mrgnet.VersionError, *mrgnet.ServerError:
+      // The server understood us and said no, asking again won't help.
+      return nil, err
+    }
+    if err != nil {
+      // Most likely this is a temporary network problem, so just keep
+      // waiting.
+      base.Warn().Printf("Unable to get game status: %v", err)
+      time.Sleep(time.Second * 5)
+      continue
+    }
+    if game == nil {
+      return nil, errors.New("Server didn't send the game.")
+    }
+    if game.Winner != 0 || (len(game.Before) >= turns && len(game.Execs) >= turns) {
+      return game, nil
+    }
+    base.Log().Printf("Found %d instead of %d states", len(game.Execs), turns)
+    time.Sleep(time.Second * 5)
+  }
+}
+
+// Loads the player's identity from the store and hands it to mrgnet.  If
+// there is no identity yet one is made, keeping the player's old NetId if
+// they have one.
+func loadIdentity() (*mrgnet.Identity, error) {
+  if text := base.GetStoreVal(store_identity); text != "" {
+    ident, err := mrgnet.UnmarshalIdentity(text)
+    if err != nil {
+      return nil, err
+    }
+    mrgnet.SetIdentity(ident)
+    return ident, nil
+  }
+  var old_id mrgnet.NetId
+  fmt.Sscanf(base.GetStoreVal(store_netid), "%d", &old_id)
+  ident, err := mrgnet.MakeIdentity(old_id)
+  if err != nil {
+    return nil, err
+  }
+  if old_id != 0 {
+    base.Log().Printf("Migrated NetId %d to a signed identity.", old_id)
+  }
+  base.SetStoreVal(store_identity, ident.Marshal())
+  mrgnet.SetIdentity(ident)
+  return ident, nil
+}
+
+// Returns the NetId of the player, loading or making their identity if
+// necessary.
+func getNetId() mrgnet.NetId {
+  if ident := mrgnet.GetIdentity(); ident != nil {
+    return ident.Id
+  }
+  ident, err := loadIdentity()
+  if err != nil {
+    base.Error().Printf("Unable to load identity: %v", err)
+    return 0
+  }
+  return ident.Id
+}
+
+// Writes the player's identity to path so that it can be restored later, or
+// on another machine, with ImportIdentity.
+func ExportIdentity(path string) error {
+  ident := mrgnet.GetIdentity()
+  if ident == nil {
+    var err error
+    ident, err = loadIdentity()
+    if err != nil {
+      return err
+    }
+  }
+  return ioutil.WriteFile(path, []byte(ident.Marshal()), 0600)
+}
+
+// Replaces the player's identity with one written by ExportIdentity.
+func ImportIdentity(path string) error {
+  data, err := ioutil.ReadFile(path)
+  if err != nil {
+    return err
+  }
+  ident, err := mrgnet.UnmarshalIdentity(string(data))
+  if err != nil {
+    return err
+  }
+  base.SetStoreVal(store_identity, ident.Marshal())
+  mrgnet.SetIdentity(ident)
+  return nil
+}
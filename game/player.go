@@ -26,7 +26,7 @@ type Player struct {
   Lua_store []byte
 
   // Game data - if the player is in the middle of a game then the state is
-  // stored here.
+  // stored here, in the same versioned format as Script.SaveGameState().
   Game_state string
 
   // Also if the player is in the middle of a game the script that should be
@@ -0,0 +1,202 @@
+package game
+
+import (
+  "encoding/gob"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game/status"
+  lua "github.com/MobRulesGames/golua"
+)
+
+func init() {
+  gob.Register(&ReadyExec{})
+}
+
+// An action that an entity has readied.  Until its side's next turn the
+// entity will use it as an interrupt on the first enemy that moves within
+// view of it.
+type ReadiedAction struct {
+  // Index into the entity's Actions
+  Index int
+
+  // Ap that was spent readying the action, this pays for the action if it
+  // goes off.
+  Ap int
+}
+
+// Readies an action instead of performing it.  This goes through the script
+// like any other exec, but the action itself never sees it.
+type ReadyExec struct {
+  BasicActionExec
+}
+
+// Returns an exec that readies action for ent, or nil if action can't be
+// readied or ent doesn't have the ap to ready it.
+func MakeReadyExec(ent *Entity, action Action) *ReadyExec {
+  if ent.Stats == nil || !action.Readyable() || action.AP() > ent.Stats.ApCur() {
+    return nil
+  }
+  var exec ReadyExec
+  exec.SetBasicData(ent, action)
+  if exec.Index == -1 {
+    return nil
+  }
+  return &exec
+}
+
+func (exec *ReadyExec) Push(L *lua.State, g *Game) {
+  exec.BasicActionExec.Push(L, g)
+  if L.IsNil(-1) {
+    return
+  }
+  L.PushString("Ready")
+  L.PushBoolean(true)
+  L.SetTable(-3)
+}
+
+// Has ent ready action, this can only be done on ent's turn when nothing else
+// is going on.  Returns true if the action will be readied.
+func (g *Game) ReadyAction(ent *Entity, action Action) bool {
+  if g.Action_state != noAction && g.Action_state != preppingAction {
+    return false
+  }
+  if g.Turn_state != turnStateAiAction || g.current_exec != nil || ent.Side() != g.Side {
+    return false
+  }
+  exec := MakeReadyExec(ent, action)
+  if exec == nil {
+    return false
+  }
+  if g.Action_state == preppingAction {
+    g.SetCurrentAction(nil)
+  }
+  g.current_exec = exec
+  return true
+}
+
+func (g *Game) doReady(exec *ReadyExec) {
+  ent := g.EntityById(exec.Ent)
+  if ent == nil || exec.Index < 0 || exec.Index >= len(ent.Actions) {
+    base.Error().Printf("Got an invalid ready exec: %v", exec)
+    return
+  }
+  action := ent.Actions[exec.Index]
+  if !action.Readyable() {
+    base.Error().Printf("Tried to ready '%s', which can't be readied.", action.String())
+    return
+  }
+  ap := action.AP()
+  if ap > ent.Stats.ApCur() {
+    base.Error().Printf("Got a ready exec that required more ap than available: %v", exec)
+    return
+  }
+  ent.Stats.ApplyDamage(-ap, 0, status.Unspecified)
+  ent.Readied = &ReadiedAction{Index: exec.Index, Ap: ap}
+  base.Log().Printf("%s readied %s", ent.Name, action.String())
+}
+
+// An interrupt that has gone off and is waiting for the action that it
+// interrupted to finish.
+type interrupt struct {
+  exec   ActionExec
+  ent    EntityId
+  target EntityId
+}
+
+func (g *Game) interruptPending(ent *Entity) bool {
+  for _, in := range g.interrupts {
+    if in.ent == ent.Id {
+      return true
+    }
+  }
+  return false
+}
+
+// Called whenever the current action says that it can be interrupted.  Every
+// ent with a readied action that can see the acting ent gets a chance to use
+// its readied action on it, and if any of them do the current action is cut
+// short where it is.  Execs that are being played back already had their
+// interrupts found when they were recorded, those interrupts are played back
+// as execs of their own.
+func (g *Game) checkInterrupts() {
+  if g.action_exec == nil || g.Turn_state == turnStateMainPhaseOver {
+    return
+  }
+  actor := g.EntityById(g.action_exec.EntityId())
+  if actor == nil || actor.Stats == nil || actor.Stats.HpCur() <= 0 {
+    return
+  }
+  x, y := actor.Pos()
+  dx, dy := actor.Dims()
+  fired := false
+  for _, ent := range g.Ents {
+    if ent.Readied == nil || ent.Stats == nil || ent.Stats.HpCur() <= 0 {
+      continue
+    }
+    if ent.Side() == actor.Side() || g.interruptPending(ent) || !ent.HasLos(x, y, dx, dy) {
+      continue
+    }
+    exec := ent.Actions[ent.Readied.Index].Interrupt(ent, actor, g)
+    if exec == nil {
+      continue
+    }
+    base.Log().Printf("%s interrupted %s", ent.Name, actor.Name)
+    readied := *ent.Readied
+    exec.SetReadiedAction(&readied)
+    g.interrupts = append(g.interrupts, interrupt{
+      exec:   exec,
+      ent:    ent.Id,
+      target: actor.Id,
+    })
+    fired = true
+  }
+  if !fired {
+    return
+  }
+  v := g.ToVertex(x, y)
+  for i, step := range g.action_exec.GetPath() {
+    if step == v {
+      g.action_exec.TruncatePath(i + 1)
+      break
+    }
+  }
+}
+
+// Starts the next interrupt that is waiting to go off, if there is one.
+// Interrupts whose ent or target died in the meantime are skipped, and the
+// ent keeps its action readied if it is still alive.
+func (g *Game) startInterrupt() {
+  for len(g.interrupts) > 0 && g.current_exec == nil {
+    next := g.interrupts[0]
+    g.interrupts = g.interrupts[1:]
+    ent := g.EntityById(next.ent)
+    target := g.EntityById(next.target)
+    if ent == nil || ent.Stats.HpCur() <= 0 {
+      continue
+    }
+    if target == nil || target.Stats == nil || target.Stats.HpCur() <= 0 {
+      continue
+    }
+    if g.Action_state == preppingAction {
+      g.SetCurrentAction(nil)
+    }
+    g.current_exec = next.exec
+  }
+}
+
+// Called as exec starts, whether it was just made or is being played back.
+// If it is an interrupt the ent's readied action is used up, and since the
+// action spends its own ap when it goes off the ap spent readying it goes
+// back to the ent first.
+func (g *Game) startReadied(exec ActionExec) {
+  readied := exec.ReadiedAction()
+  if readied == nil {
+    return
+  }
+  ent := g.EntityById(exec.EntityId())
+  if ent == nil || ent.Stats == nil {
+    base.Error().Printf("Got an interrupt exec for an invalid entity: %v", exec)
+    return
+  }
+  ent.Stats.ApplyDamage(readied.Ap, 0, status.Unspecified)
+  ent.Readied = nil
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+  "crypto/sha1"
+  "errors"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "io"
+  "os"
+  "path/filepath"
+  "regexp"
+  "strings"
+)
+
+const replay_extension = ".replay"
+
+// Directories, relative to the data dir, whose contents determine how a game
+// plays out.  Anything else, like textures and sounds, is cosmetic.
+var gameplay_data_dirs = []string{
+  "actions", "ais", "conditions", "doors", "entities", "furniture", "gear",
+  "houses", "objects", "rooms", "scripts", "spawns",
+}
+
+func replayDir() string {
+  return filepath.Join(base.GetDataDir(), "replays")
+}
+
+// Returns a hash of all of the json and lua files that affect gameplay.  Two
+// installs with the same checksum will play back the same replay the same
+// way.
+func dataChecksum() ([]byte, error) {
+  hash := sha1.New()
+  datadir := base.GetDataDir()
+  for _, dir := range gameplay_data_dirs {
+    err := filepath.Walk(filepath.Join(datadir, dir), func(path string, info os.FileInfo, err error) error {
+      if err != nil {
+        return err
+      }
+      if info.IsDir() {
+        return nil
+      }
+      ext := filepath.Ext(path)
+      if ext != ".json" && ext != ".lua" {
+        return nil
+      }
+      rel, err := filepath.Rel(datadir, path)
+      if err != nil {
+        return err
+      }
+      f, err := os.Open(path)
+      if err != nil {
+        return err
+      }
+      defer f.Close()
+      io.WriteString(hash, filepath.ToSlash(rel))
+      _, err = io.Copy(hash, f)
+      return err
+    })
+    if err != nil && !os.IsNotExist(err) {
+      return nil, err
+    }
+  }
+  return hash.Sum(nil), nil
+}
+
+var load_house_regexp = regexp.MustCompile(`LoadHouse\("([^"]*)"\)`)
+
+// Returns the name of the house that a level script loads, or an empty
+// string if it can't tell.
+func scriptHouse(script []byte) string {
+  match := load_house_regexp.FindSubmatch(script)
+  if match == nil {
+    return ""
+  }
+  return string(match[1])
+}
+
+// Downloads the complete history of an online game and writes it to the
+// replay directory, returning the path of the file it wrote.
+func ExportReplay(key mrgnet.GameKey) (string, error) {
+  var resp mrgnet.StatusResponse
+  err := mrgnet.DoAction("status", mrgnet.StatusRequest{Id: getNetId(), Game_key: key}, &resp)
+  if err != nil {
+    return "", err
+  }
+  if resp.Err != "" {
+    return "", errors.New(resp.Err)
+  }
+  if resp.Game == nil || len(resp.Game.Execs) == 0 {
+    return "", errors.New("Nothing has happened in this game yet.")
+  }
+  replay := mrgnet.MakeReplay(resp.Game)
+  replay.House = scriptHouse(replay.Script)
+  replay.Data_checksum, err = dataChecksum()
+  if err != nil {
+    return "", err
+  }
+
+  if err := os.MkdirAll(replayDir(), 0755); err != nil {
+    return "", err
+  }
+  name := strings.Map(func(r rune) rune {
+    if strings.ContainsRune(`/\:*?"<>|`, r) {
+      return '_'
+    }
+    return r
+  }, resp.Game.Name)
+  path := filepath.Join(replayDir(), fmt.Sprintf("%s-%s%s", name, key, replay_extension))
+  f, err := os.Create(path)
+  if err != nil {
+    return "", err
+  }
+  defer f.Close()
+  if err := mrgnet.WriteReplay(f, replay); err != nil {
+    return "", err
+  }
+  base.Log().Printf("Exported game %s to %s", key, path)
+  return path, nil
+}
+
+// Loads a replay written by ExportReplay.  If the replay was made with
+// different game data than what is installed it is still loaded, but a
+// warning is logged since it might not play back correctly.
+func LoadReplay(path string) (*mrgnet.Replay, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  replay, err := mrgnet.ReadReplay(f)
+  if err != nil {
+    return nil, err
+  }
+  if len(replay.Before) != replay.Turns || len(replay.Execs) != replay.Turns {
+    return nil, fmt.Errorf("Replay %s is incomplete.", path)
+  }
+  checksum, err := dataChecksum()
+  if err != nil {
+    base.Warn().Printf("Unable to checksum game data: %v", err)
+  } else if string(checksum) != string(replay.Data_checksum) {
+    base.Warn().Printf("Replay %s was made with different game data, it might not play back correctly.", path)
+  }
+  return replay, nil
+}
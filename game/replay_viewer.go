@@ -0,0 +1,251 @@
+package game
+
+import (
+  "bytes"
+  "fmt"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "time"
+)
+
+// How long to wait between execs when a replay is playing on its own.
+const replay_step_delay = 250 * time.Millisecond
+
+type replayCommandKind int
+
+const (
+  replayStep replayCommandKind = iota
+  replayPlay
+  replayPause
+  replayRewind
+  replayJump
+  replayToggleLos
+
+  // Stops playing the replay for good.
+  replayQuit
+)
+
+type replayCommand struct {
+  kind replayCommandKind
+
+  // Only for replayJump, the round to jump to, counting from one.
+  round int
+}
+
+// Keeps track of where we are in a replay.  The replay is played back from
+// the script's go-routine, the only snapshots we have are the Before states
+// of each turn, so rewinding goes back to the start of a turn.
+type replayPlayer struct {
+  replay *mrgnet.Replay
+
+  // The turn we're in and the index of the next exec to play.  num_execs is
+  // the number of execs in this turn.  These are only changed while synced
+  // with the game so that they can be read from Think.
+  turn, exec int
+  num_execs  int
+  playing    bool
+
+  // Side whose los we see.
+  follow Side
+
+  commands chan replayCommand
+
+  // If not nil, every replayStep sends whether it played an exec on this.
+  stepped chan bool
+}
+
+// Makes a GamePanel that plays back a replay.  Nothing is sent to the server
+// and the player can't do anything other than control the playback.
+func MakeReplayPanel(replay *mrgnet.Replay) *GamePanel {
+  var gp GamePanel
+  gp.AnchorBox = gui.MakeAnchorBox(gui.Dims{1024, 768})
+  startReplayScript(&gp, replay)
+  return &gp
+}
+
+func startReplayScript(gp *GamePanel, replay *mrgnet.Replay) {
+  base.Log().Printf("startReplayScript")
+  makeGameScript(gp, &Player{}, "")
+  gp.script.L.NewTable()
+  gp.script.L.SetGlobal("store")
+  gp.script.sync = make(chan struct{})
+  gp.script.replay = &replayPlayer{
+    replay:   replay,
+    follow:   SideHaunt,
+    commands: make(chan replayCommand, 1),
+  }
+
+  go func() {
+    if len(replay.Before) == 0 {
+      base.Error().Printf("Replay %s is empty.", replay.Name)
+      return
+    }
+    if !gp.script.L.DoString(string(replay.Script)) {
+      base.Error().Printf("There was an error running the script for replay %s.", replay.Name)
+      return
+    }
+    controls, err := MakeReplayControls(gp)
+    if err != nil {
+      base.Error().Printf("Unable to make replay controls: %v", err)
+      return
+    }
+    gp.replay = controls
+    loadGameStateRaw(gp, gp.script.L, string(replay.Before[0]))
+    if gp.game == nil {
+      base.Error().Printf("Unable to load the first turn of replay %s.", replay.Name)
+      return
+    }
+    gp.game.net.side = SideHaunt
+    gp.game.net.spectator = true
+    gp.script.L.DoString("OnStartup()")
+    gp.game.SetVisibility(SideHaunt)
+    gp.game.silenceAis()
+    gp.game.comm.script_to_game <- nil
+  }()
+}
+
+// Replays never get past the first round as far as the game is concerned,
+// once the main phase is over we just hand the game whatever execs the
+// player asks for.
+func (gs *gameScript) OnRoundReplaying(g *Game) {
+  go func() {
+    // signals to the game that we're done with the startup stuff
+    g.comm.script_to_game <- nil
+
+    _exec := <-g.comm.game_to_script
+    if _exec != nil {
+      panic("Got an exec when we shouldn't have gotten one.")
+    }
+
+    rp := gs.replay
+    rp.loadTurn(gs, g, 0)
+    for {
+      var cmd replayCommand
+      if rp.playing {
+        select {
+        case cmd = <-rp.commands:
+        case <-time.After(replay_step_delay):
+          cmd.kind = replayStep
+        }
+      } else {
+        cmd = <-rp.commands
+      }
+      if cmd.kind == replayQuit {
+        return
+      }
+      rp.do(gs, g, cmd)
+    }
+  }()
+}
+
+func (rp *replayPlayer) do(gs *gameScript, g *Game, cmd replayCommand) {
+  switch cmd.kind {
+  case replayStep:
+    played := false
+    switch {
+    case rp.exec < rp.num_execs:
+      rp.playExec(gs)
+      played = true
+    case rp.turn+1 < len(rp.replay.Before):
+      rp.loadTurn(gs, g, rp.turn+1)
+    default:
+      rp.setPlaying(gs, false)
+    }
+    if rp.stepped != nil {
+      rp.stepped <- played
+    }
+
+  case replayPlay:
+    rp.setPlaying(gs, true)
+
+  case replayPause:
+    rp.setPlaying(gs, false)
+
+  case replayRewind:
+    switch {
+    case rp.exec > 0:
+      rp.loadTurn(gs, g, rp.turn)
+    case rp.turn > 0:
+      rp.loadTurn(gs, g, rp.turn-1)
+    }
+
+  case replayJump:
+    turn := 2 * (cmd.round - 1)
+    if turn >= len(rp.replay.Before) {
+      turn = len(rp.replay.Before) - 1
+    }
+    if turn < 0 {
+      turn = 0
+    }
+    rp.loadTurn(gs, g, turn)
+
+  case replayToggleLos:
+    gs.syncStart()
+    if rp.follow == SideHaunt {
+      rp.follow = SideExplorers
+    } else {
+      rp.follow = SideHaunt
+    }
+    g.net.side = rp.follow
+    g.SetVisibility(rp.follow)
+    gs.syncEnd()
+  }
+}
+
+func (rp *replayPlayer) setPlaying(gs *gameScript, playing bool) {
+  gs.syncStart()
+  rp.playing = playing
+  gs.syncEnd()
+}
+
+// Restores the snapshot from the start of the specified turn and gets its
+// execs ready to be played.
+func (rp *replayPlayer) loadTurn(gs *gameScript, g *Game, turn int) {
+  gs.L.SetExecutionLimit(250000)
+  gs.L.PushString(string(rp.replay.Before[turn]))
+  gs.L.SetGlobal("__state")
+  gs.L.DoString("Script.LoadGameState(__state)")
+
+  gs.syncStart()
+  err := LuaDecodeValue(bytes.NewBuffer(rp.replay.Execs[turn]), gs.L, g)
+  gs.syncEnd()
+  num_execs := 0
+  if err != nil {
+    base.Error().Printf("Unable to decode execs for turn %d: %v", turn, err)
+    gs.L.NewTable()
+  } else {
+    gs.L.PushNil()
+    for gs.L.Next(-2) != 0 {
+      num_execs++
+      gs.L.Pop(1)
+    }
+  }
+  gs.L.SetGlobal("__execs")
+
+  gs.syncStart()
+  rp.turn = turn
+  rp.exec = 0
+  rp.num_execs = num_execs
+  g.net.side = rp.follow
+  g.SetVisibility(rp.follow)
+  g.silenceAis()
+  gs.syncEnd()
+}
+
+// Plays the next exec, with the script's PlaybackExec() if it has one.
+func (rp *replayPlayer) playExec(gs *gameScript) {
+  gs.L.SetExecutionLimit(250000)
+  gs.L.DoString(fmt.Sprintf("__exec = __execs[%d]", rp.exec+1))
+  gs.L.GetGlobal("PlaybackExec")
+  has_playback := !gs.L.IsNil(-1)
+  gs.L.Pop(1)
+  if has_playback {
+    gs.L.DoString("PlaybackExec(__exec)")
+  } else {
+    gs.L.DoString("Script.DoExec(__exec)")
+  }
+  gs.syncStart()
+  rp.exec++
+  gs.syncEnd()
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+  "bytes"
+  "encoding/base64"
+  "encoding/gob"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+)
+
+// Version of the save format written by encodeGameState.  Any change to
+// Game, Entity, or any of the actions that would keep an older save from
+// decoding properly needs to bump this and register a migration from the
+// previous version.
+const Save_version = 1
+
+const save_magic = "haunts save"
+
+// Written at the start of every save, on its own, so that we know how to
+// read the rest of it before we try.
+type saveHeader struct {
+  Magic   string
+  Version int
+}
+
+// Everything that is saved, this is what follows the header.
+type totalState struct {
+  Game  **Game
+  Store []byte
+}
+
+// A saveMigration takes the gob data that follows the header in a save of
+// one version and returns the equivalent data for the next version.
+type saveMigration func(data []byte) ([]byte, error)
+
+// save_migrations[v] upgrades a version v save to version v+1.
+var save_migrations = make(map[int]saveMigration)
+
+func registerSaveMigration(from int, m saveMigration) {
+  if _, ok := save_migrations[from]; ok {
+    panic(fmt.Sprintf("Registered two save migrations from version %d.", from))
+  }
+  save_migrations[from] = m
+}
+
+func init() {
+  // Saves from before there was a header are version 0, other than the
+  // header they're identical to version 1.
+  registerSaveMigration(0, func(data []byte) ([]byte, error) {
+    return data, nil
+  })
+}
+
+// Separates the header from the rest of a save.  Saves without a header are
+// treated as version 0.
+func readSaveHeader(data []byte) (int, []byte) {
+  buf := bytes.NewBuffer(data)
+  var header saveHeader
+  // A bytes.Buffer is an io.ByteReader so the decoder doesn't read past the
+  // header.
+  if err := gob.NewDecoder(buf).Decode(&header); err != nil || header.Magic != save_magic {
+    return 0, data
+  }
+  return header.Version, buf.Bytes()
+}
+
+// Returns the body of a save of any supported version as the body of a
+// save of the current version.
+func upgradeSave(data []byte) ([]byte, error) {
+  version, body := readSaveHeader(data)
+  if version > Save_version {
+    return nil, fmt.Errorf("This save is from a newer version of Haunts, it is save version %d and this version can only read up to %d.", version, Save_version)
+  }
+  for ; version < Save_version; version++ {
+    migrate, ok := save_migrations[version]
+    if !ok {
+      return nil, fmt.Errorf("Save version %d is no longer supported.", version)
+    }
+    var err error
+    body, err = migrate(body)
+    if err != nil {
+      return nil, fmt.Errorf("Unable to upgrade save from version %d: %v", version, err)
+    }
+    base.Log().Printf("Upgraded save from version %d to %d", version, version+1)
+  }
+  return body, nil
+}
+
+// Encodes g and the script's store as a save of the current version.
+func encodeGameState(g *Game, store []byte) (string, error) {
+  buf := bytes.NewBuffer(nil)
+  enc := gob.NewEncoder(buf)
+  if err := enc.Encode(saveHeader{Magic: save_magic, Version: Save_version}); err != nil {
+    return "", err
+  }
+  // The body gets its own encoder so that migrations can treat it as a
+  // complete gob stream on its own.
+  if err := gob.NewEncoder(buf).Encode(totalState{Game: &g, Store: store}); err != nil {
+    return "", err
+  }
+  return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decodes a save made by encodeGameState into *g, upgrading it first if it
+// is from an older version, and returns the script's store.  Nothing is
+// decoded if the save can't be upgraded.
+func decodeGameState(state string, g **Game) ([]byte, error) {
+  data, err := base64.StdEncoding.DecodeString(state)
+  if err != nil {
+    return nil, err
+  }
+  body, err := upgradeSave(data)
+  if err != nil {
+    return nil, err
+  }
+  ts := totalState{Game: g}
+  if err := gob.NewDecoder(bytes.NewBuffer(body)).Decode(&ts); err != nil {
+    return nil, err
+  }
+  return ts.Store, nil
+}
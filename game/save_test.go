@@ -0,0 +1,74 @@
+package game_test
+
+import (
+  "encoding/base64"
+  "path/filepath"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game"
+)
+
+var datadir string
+
+func init() {
+  datadir, _ = filepath.Abs("../data_test")
+  base.SetDatadir(datadir)
+}
+
+// Loads data_test the same way the tools load their data.
+func loadTestRegistries() {
+  base.SetHeadless(true)
+  base.InitShaders()
+  game.LoadAllRegistries(datadir)
+}
+
+func SaveSpec(c gospec.Context) {
+  loadTestRegistries()
+  g := game.MakeGameInHouse("test")
+  g.SeedRand(1234)
+  g.Turn = 3
+  state, err := game.EncodeGameState(g, []byte("store"))
+  c.Assume(err, Equals, nil)
+  data, err := base64.StdEncoding.DecodeString(state)
+  c.Assume(err, Equals, nil)
+  version, body := game.ReadSaveHeader(data)
+  c.Assume(version, Equals, game.Save_version)
+
+  c.Specify("Saves keep the game and the store.", func() {
+    g2, store, err := game.DecodeGameState(state)
+    c.Assume(err, Equals, nil)
+    c.Expect(string(store), Equals, "store")
+    c.Expect(g2.Seed, Equals, g.Seed)
+    c.Expect(g2.Turn, Equals, 3)
+    c.Expect(g2.Side, Equals, g.Side)
+    c.Expect(g2.House.Name, Equals, "test")
+    c.Expect(g2.Rand.Int63(), Equals, g.Rand.Int63())
+  })
+
+  c.Specify("Saves from before there was a header are upgraded.", func() {
+    v0, _ := game.ReadSaveHeader(body)
+    c.Expect(v0, Equals, 0)
+    upgraded, err := game.UpgradeSave(body)
+    c.Assume(err, Equals, nil)
+    c.Expect(string(upgraded), Equals, string(body))
+    g2, store, err := game.DecodeGameState(base64.StdEncoding.EncodeToString(body))
+    c.Assume(err, Equals, nil)
+    c.Expect(string(store), Equals, "store")
+    c.Expect(g2.Turn, Equals, 3)
+  })
+
+  c.Specify("Saves from a newer version are rejected.", func() {
+    _, err := game.UpgradeSave(game.AddSaveHeader(game.Save_version+1, body))
+    c.Expect(err, Not(Equals), nil)
+  })
+
+  c.Specify("Saves that can't be upgraded all the way are rejected.", func() {
+    game.WithoutSaveMigration(0, func() {
+      _, err := game.UpgradeSave(body)
+      c.Expect(err, Not(Equals), nil)
+      _, _, err = game.DecodeGameState(base64.StdEncoding.EncodeToString(body))
+      c.Expect(err, Not(Equals), nil)
+    })
+  })
+}
@@ -0,0 +1,202 @@
+package game
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/gob"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "hash/fnv"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sort"
+  "time"
+)
+
+const save_extension = ".save"
+
+const autosave_name = "Autosave"
+
+func savesDir() string {
+  return filepath.Join(base.GetDataDir(), "saves")
+}
+
+// Everything that is shown about a save when listing them.  It is written at
+// the start of a save file, ahead of the player, so that the saves can be
+// listed without decoding every game.
+type SaveInfo struct {
+  Name string
+
+  // The level's script, relative to the scripts directory.
+  Script string
+
+  // The round, and the side whose turn it was, when the game was saved.
+  Round int
+  Side  string
+
+  Time     time.Time
+  Autosave bool
+
+  // The file the save was read from, this isn't saved.
+  path string
+}
+
+func (si SaveInfo) Path() string {
+  return si.path
+}
+
+// Every named save gets its own file, there is only ever one autosave and
+// every new one replaces the last.
+func saveSlotPath(name string, autosave bool) string {
+  if autosave {
+    return filepath.Join(savesDir(), "autosave"+save_extension)
+  }
+  hash := fnv.New64()
+  hash.Write([]byte(name))
+  return filepath.Join(savesDir(), fmt.Sprintf("%x%s", hash.Sum64(), save_extension))
+}
+
+// Saves g and the script's store in the slot called name, replacing whatever
+// was there.  A save file is a SaveInfo followed by a Player, written with
+// EncodePlayer, with the player's Game_state set so that LoadSave and
+// MakeGamePanel pick the game up where it was left.
+func (gs *gameScript) saveToSlot(g *Game, name string, autosave bool) error {
+  p := *gs.player
+  p.No_init = true
+  return gs.writeSlot(g, &p, name, autosave)
+}
+
+// Like saveToSlot, but p is saved as it is rather than a copy of the
+// script's player, and is updated along with the save.
+func (gs *gameScript) writeSlot(g *Game, p *Player, name string, autosave bool) error {
+  UpdatePlayer(p, gs.L)
+  buf := bytes.NewBuffer(nil)
+  gs.L.GetGlobal("store")
+  LuaEncodeValue(buf, gs.L, -1)
+  gs.L.Pop(1)
+  state, err := encodeGameState(g, buf.Bytes())
+  if err != nil {
+    return err
+  }
+  p.Name = name
+  p.Game_state = state
+
+  info := SaveInfo{
+    Name:     name,
+    Script:   p.Script_path,
+    Round:    (g.Turn + 1) / 2,
+    Side:     sideName(g.Side),
+    Time:     time.Now(),
+    Autosave: autosave,
+  }
+
+  if err := os.MkdirAll(savesDir(), 0755); err != nil {
+    return err
+  }
+  return writeSave(saveSlotPath(name, autosave), info, p)
+}
+
+// Writes to a temporary file and then renames it over path so that a crash
+// in the middle of a save never leaves the slot half-written.
+func writeSave(path string, info SaveInfo, p *Player) error {
+  tmp := path + ".tmp"
+  f, err := os.Create(tmp)
+  if err != nil {
+    return err
+  }
+  err = gob.NewEncoder(f).Encode(info)
+  if err == nil {
+    err = EncodePlayer(f, p)
+  }
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    os.Remove(tmp)
+    return err
+  }
+  return os.Rename(tmp, path)
+}
+
+// Reads the SaveInfo from the start of the save file at path, if p is not
+// nil the player that follows it is decoded into *p as well.
+func readSave(path string, p **Player) (SaveInfo, error) {
+  var info SaveInfo
+  f, err := os.Open(path)
+  if err != nil {
+    return info, err
+  }
+  defer f.Close()
+  // A bufio.Reader is an io.ByteReader so the first decoder doesn't read
+  // past the info, leaving the player for DecodePlayer.
+  r := bufio.NewReader(f)
+  if err := gob.NewDecoder(r).Decode(&info); err != nil {
+    return info, err
+  }
+  info.path = path
+  if p != nil {
+    *p, err = DecodePlayer(r)
+  }
+  return info, err
+}
+
+// Returns the info of every save that we can read, the autosave first and
+// then the rest from newest to oldest.
+func ListSaves() []SaveInfo {
+  infos, err := ioutil.ReadDir(savesDir())
+  if err != nil && !os.IsNotExist(err) {
+    base.Warn().Printf("Unable to list saves: %v", err)
+  }
+  var saves []SaveInfo
+  for _, info := range infos {
+    if info.IsDir() || filepath.Ext(info.Name()) != save_extension {
+      continue
+    }
+    path := filepath.Join(savesDir(), info.Name())
+    save, err := readSave(path, nil)
+    if err != nil {
+      base.Warn().Printf("Unable to read save %s: %v", path, err)
+      continue
+    }
+    saves = append(saves, save)
+  }
+  sort.Sort(savesByTime(saves))
+  return saves
+}
+
+type savesByTime []SaveInfo
+
+func (s savesByTime) Len() int      { return len(s) }
+func (s savesByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s savesByTime) Less(i, j int) bool {
+  if s[i].Autosave != s[j].Autosave {
+    return s[i].Autosave
+  }
+  return s[i].Time.After(s[j].Time)
+}
+
+// Returns the player stored in the save at path, passing it to MakeGamePanel
+// resumes the saved game.
+func LoadSave(path string) (*Player, error) {
+  var p *Player
+  _, err := readSave(path, &p)
+  if err != nil {
+    return nil, err
+  }
+  return p, nil
+}
+
+// Replaces the player in the save at path with p, leaving the rest of the
+// save as it was.
+func UpdateSave(path string, p *Player) error {
+  info, err := readSave(path, nil)
+  if err != nil {
+    return err
+  }
+  return writeSave(path, info, p)
+}
+
+func DeleteSave(path string) error {
+  return os.Remove(path)
+}
@@ -0,0 +1,91 @@
+package game_test
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "time"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game"
+)
+
+func SaveSlotSpec(c gospec.Context) {
+  dir, err := ioutil.TempDir("", "saves")
+  c.Assume(err, Equals, nil)
+  defer os.RemoveAll(dir)
+  base.SetDatadir(dir)
+  defer base.SetDatadir(datadir)
+
+  now := time.Now()
+  save := func(name string, autosave bool, age time.Duration) {
+    p := &game.Player{Script_path: "test.lua", Game_state: name}
+    c.Assume(game.WriteSaveSlot(p, name, autosave, now.Add(-age)), Equals, nil)
+  }
+  names := func() []string {
+    var names []string
+    for _, info := range game.ListSaves() {
+      names = append(names, info.Name)
+    }
+    return names
+  }
+
+  c.Specify("Slots are files in the saves directory.", func() {
+    save("Foo", false, 0)
+    save("Autosave", true, 0)
+    saves := game.ListSaves()
+    c.Assume(len(saves), Equals, 2)
+    for _, info := range saves {
+      c.Expect(filepath.Dir(info.Path()), Equals, filepath.Join(dir, "saves"))
+      c.Expect(filepath.Ext(info.Path()), Equals, ".save")
+    }
+    c.Expect(filepath.Base(saves[0].Path()), Equals, "autosave.save")
+    files, err := ioutil.ReadDir(filepath.Join(dir, "saves"))
+    c.Assume(err, Equals, nil)
+    c.Expect(len(files), Equals, 2)
+    players, err := ioutil.ReadDir(filepath.Join(dir, "players"))
+    c.Expect(os.IsNotExist(err), Equals, true)
+    c.Expect(len(players), Equals, 0)
+  })
+
+  c.Specify("Saving to a slot again replaces it.", func() {
+    save("Foo", false, time.Hour)
+    save("Foo", false, 0)
+    save("Autosave", true, time.Hour)
+    save("Autosave", true, 0)
+    saves := game.ListSaves()
+    c.Assume(len(saves), Equals, 2)
+    c.Expect(saves[0].Time.Equal(now), Equals, true)
+    c.Expect(saves[1].Time.Equal(now), Equals, true)
+    files, err := ioutil.ReadDir(filepath.Join(dir, "saves"))
+    c.Assume(err, Equals, nil)
+    c.Expect(len(files), Equals, 2)
+  })
+
+  c.Specify("The autosave is listed first, then the rest from newest to oldest.", func() {
+    save("Middle", false, 2*time.Hour)
+    save("Autosave", true, 3*time.Hour)
+    save("Newest", false, time.Hour)
+    save("Oldest", false, 4*time.Hour)
+    c.Expect(names(), ContainsInOrder, Values("Autosave", "Newest", "Middle", "Oldest"))
+  })
+
+  c.Specify("Loading a save returns the player that was saved in it.", func() {
+    save("Foo", false, 0)
+    saves := game.ListSaves()
+    c.Assume(len(saves), Equals, 1)
+    p, err := game.LoadSave(saves[0].Path())
+    c.Assume(err, Equals, nil)
+    c.Expect(p.Game_state, Equals, "Foo")
+    c.Expect(p.Script_path, Equals, "test.lua")
+  })
+
+  c.Specify("Deleted saves aren't listed.", func() {
+    save("Foo", false, time.Hour)
+    save("Bar", false, 0)
+    c.Assume(len(game.ListSaves()), Equals, 2)
+    c.Expect(game.DeleteSave(game.ListSaves()[0].Path()), Equals, nil)
+    c.Expect(names(), ContainsExactly, Values("Foo"))
+  })
+}
@@ -0,0 +1,190 @@
+package game
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "runtime"
+  "strings"
+  "time"
+)
+
+// How far the game is advanced every time a Scenario thinks, and how long it
+// will wait on the game before giving up.
+const scenario_dt = 10
+const scenario_timeout = 10 * time.Second
+
+// A Scenario runs a level script headless so that tests can play it one
+// exec at a time and check what happened after each one.  The script should
+// bind both sides to "human", then nothing happens in the game other than
+// the execs passed to Exec and the turns ended by EndTurn.
+type Scenario struct {
+  *HeadlessGame
+  input *scenarioInput
+}
+
+// Keeps track of every dialog that the script shows, levels show a dialog
+// with Victory_Denizens or Victory_Intruders in its name when a side wins.
+type scenarioInput struct {
+  AutoInput
+  dialogs []string
+}
+
+func (si *scenarioInput) Dialog(path string, args map[string]string) []string {
+  si.dialogs = append(si.dialogs, path)
+  return nil
+}
+
+// Starts script, which is relative to the scripts directory, and waits until
+// it has loaded a house and the first turn is ready to be played.  data is
+// passed to the script's Init function, seed is used as in
+// MakeHeadlessGame.
+func MakeScenario(script string, data map[string]string, seed int64) (*Scenario, error) {
+  input := &scenarioInput{}
+  s := &Scenario{
+    HeadlessGame: MakeHeadlessGame(script, nil, data, input, seed),
+    input:        input,
+  }
+  // Every Think plays out whole actions and animations, so the game only
+  // ever has to wait on the script.
+  s.SetClock(StepClock{scenario_dt})
+  s.SetFastForward(true)
+  if err := s.thinkUntil(s.ready); err != nil {
+    s.Close()
+    return nil, fmt.Errorf("Script %s never started: %v", script, err)
+  }
+  return s, nil
+}
+
+// Returns true if the game is waiting for the player to do something.
+func (s *Scenario) ready() bool {
+  g := s.Game()
+  if g == nil || g.Turn_state != turnStateAiAction {
+    return false
+  }
+  if g.Action_state != noAction || g.current_action != nil || g.current_exec != nil {
+    return false
+  }
+  return !g.animating()
+}
+
+// Thinks until done returns true or the script ends the game.
+func (s *Scenario) thinkUntil(done func() bool) error {
+  start := time.Now()
+  for !s.Over() && !done() {
+    if time.Since(start) > scenario_timeout {
+      return errors.New("Timed out waiting on the game.")
+    }
+    s.Think(scenario_dt)
+    // The script runs in its own go routine, it needs a chance to catch up.
+    runtime.Gosched()
+  }
+  return nil
+}
+
+// Runs exec and waits until it, and the script's OnAction for it, have
+// completed.  exec can be made with any of the Ai* methods on the actions,
+// those return nil if the action isn't possible, which is reported as an
+// error here.
+func (s *Scenario) Exec(exec ActionExec) error {
+  if exec == nil {
+    return errors.New("No exec to run, the action probably wasn't possible.")
+  }
+  if s.Over() {
+    return errors.New("The game is already over.")
+  }
+  if err := s.thinkUntil(s.ready); err != nil {
+    return err
+  }
+  g := s.Game()
+  if g.EntityById(exec.EntityId()) == nil {
+    return fmt.Errorf("No entity with id %d.", exec.EntityId())
+  }
+  base.Log().Printf("Scenario: running %v", exec)
+  g.current_exec = exec
+  return s.thinkUntil(s.ready)
+}
+
+// Ends the current turn and waits until the other side can play.
+func (s *Scenario) EndTurn() error {
+  if err := s.thinkUntil(s.ready); err != nil {
+    return err
+  }
+  turn := s.Game().Turn
+  s.HeadlessGame.EndTurn()
+  return s.thinkUntil(func() bool {
+    return s.Game().Turn != turn && s.ready()
+  })
+}
+
+// Returns the first entity named name, or nil if there isn't one.  Ents that
+// are killed stay in the game until the end of the turn.
+func (s *Scenario) Ent(name string) *Entity {
+  for _, ent := range s.Game().Ents {
+    if ent.Name == name {
+      return ent
+    }
+  }
+  return nil
+}
+
+// Returns the game, and the script's store, encoded the same way as
+// Script.SaveGameState.  This is what online games upload as the state before
+// and after every turn.
+func (s *Scenario) SaveGameState() ([]byte, error) {
+  if err := s.thinkUntil(s.ready); err != nil {
+    return nil, err
+  }
+  store, err := s.encodeGlobal("store")
+  if err != nil {
+    return nil, err
+  }
+  state, err := encodeGameState(s.Game(), store)
+  if err != nil {
+    return nil, err
+  }
+  return []byte(state), nil
+}
+
+// Returns store.execs encoded the same way as Net.UpdateExecs, scripts that
+// can be played online keep every exec of the current turn there.
+func (s *Scenario) Execs() ([]byte, error) {
+  if err := s.thinkUntil(s.ready); err != nil {
+    return nil, err
+  }
+  return s.encodeGlobal("store.execs")
+}
+
+// Encodes the value of the lua expression expr with LuaEncodeValue.
+func (s *Scenario) encodeGlobal(expr string) ([]byte, error) {
+  L := s.gp.script.L
+  if !L.DoString("__scenario = " + expr) {
+    return nil, fmt.Errorf("Unable to evaluate '%s'.", expr)
+  }
+  L.GetGlobal("__scenario")
+  defer L.Pop(1)
+  buf := bytes.NewBuffer(nil)
+  if err := LuaEncodeValue(buf, L, -1); err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
+
+// Returns the paths of every dialog the script has shown, in order.
+func (s *Scenario) Dialogs() []string {
+  return s.input.dialogs
+}
+
+// Returns "Denizens" or "Intruders" once the script has shown that side's
+// victory dialog, or an empty string if nobody has won yet.
+func (s *Scenario) Winner() string {
+  for _, path := range s.input.dialogs {
+    for _, side := range []string{"Denizens", "Intruders"} {
+      if strings.Contains(path, "Victory_"+side) {
+        return side
+      }
+    }
+  }
+  return ""
+}
@@ -17,6 +17,7 @@ import (
   "io/ioutil"
   "path/filepath"
   "regexp"
+  "strings"
   "time"
 )
 
@@ -26,6 +27,20 @@ type gameScript struct {
   // Since the scripts can do anything they want sometimes we want make sure
   // certain things only run when the game is ready for them.
   sync chan struct{}
+
+  // Number of turns that have been replayed, only used by spectators.
+  spectated int
+
+  // Only set when watching a replay.
+  replay *replayPlayer
+
+  // The player this script was started for, saves are made from a copy of
+  // it.
+  player *Player
+
+  // If set the game is saved to the autosave slot at the start of every
+  // round.
+  autosave bool
 }
 
 func (gs *gameScript) syncStart() {
@@ -56,81 +71,10 @@ func startGameScript(gp *GamePanel, path string, player *Player, data map[string
       return
     }
   }
-  gp.script = &gameScript{}
-  base.Log().Printf("script = %p", gp.script)
-
-  gp.script.L = lua.NewState()
-  gp.script.L.OpenLibs()
-  gp.script.L.SetExecutionLimit(25000)
-  gp.script.L.NewTable()
-  LuaPushSmartFunctionTable(gp.script.L, FunctionTable{
-    "ChooserFromFile":                   func() { gp.script.L.PushGoFunction(chooserFromFile(gp)) },
-    "StartScript":                       func() { gp.script.L.PushGoFunction(startScript(gp, player)) },
-    "GameOnRound":                       func() { gp.script.L.PushGoFunction(doGameOnRound(gp)) },
-    "SaveGameState":                     func() { gp.script.L.PushGoFunction(saveGameState(gp)) },
-    "LoadGameState":                     func() { gp.script.L.PushGoFunction(loadGameState(gp)) },
-    "DoExec":                            func() { gp.script.L.PushGoFunction(doExec(gp)) },
-    "SelectEnt":                         func() { gp.script.L.PushGoFunction(selectEnt(gp)) },
-    "FocusPos":                          func() { gp.script.L.PushGoFunction(focusPos(gp)) },
-    "FocusZoom":                         func() { gp.script.L.PushGoFunction(focusZoom(gp)) },
-    "SelectHouse":                       func() { gp.script.L.PushGoFunction(selectHouse(gp)) },
-    "LoadHouse":                         func() { gp.script.L.PushGoFunction(loadHouse(gp)) },
-    "SaveStore":                         func() { gp.script.L.PushGoFunction(saveStore(gp, player)) },
-    "ShowMainBar":                       func() { gp.script.L.PushGoFunction(showMainBar(gp, player)) },
-    "SpawnEntityAtPosition":             func() { gp.script.L.PushGoFunction(spawnEntityAtPosition(gp)) },
-    "GetSpawnPointsMatching":            func() { gp.script.L.PushGoFunction(getSpawnPointsMatching(gp)) },
-    "SpawnEntitySomewhereInSpawnPoints": func() { gp.script.L.PushGoFunction(spawnEntitySomewhereInSpawnPoints(gp)) },
-    "IsSpawnPointInLos":                 func() { gp.script.L.PushGoFunction(isSpawnPointInLos(gp)) },
-    "PlaceEntities":                     func() { gp.script.L.PushGoFunction(placeEntities(gp)) },
-    "RoomAtPos":                         func() { gp.script.L.PushGoFunction(roomAtPos(gp)) },
-    "SetLosMode":                        func() { gp.script.L.PushGoFunction(setLosMode(gp)) },
-    "GetAllEnts":                        func() { gp.script.L.PushGoFunction(getAllEnts(gp)) },
-    "DialogBox":                         func() { gp.script.L.PushGoFunction(dialogBox(gp)) },
-    "PickFromN":                         func() { gp.script.L.PushGoFunction(pickFromN(gp)) },
-    "SetGear":                           func() { gp.script.L.PushGoFunction(setGear(gp)) },
-    "BindAi":                            func() { gp.script.L.PushGoFunction(bindAi(gp)) },
-    "SetVisibility":                     func() { gp.script.L.PushGoFunction(setVisibility(gp)) },
-    "EndPlayerInteraction":              func() { gp.script.L.PushGoFunction(endPlayerInteraction(gp)) },
-    "GetLos":                            func() { gp.script.L.PushGoFunction(getLos(gp)) },
-    "SetVisibleSpawnPoints":             func() { gp.script.L.PushGoFunction(setVisibleSpawnPoints(gp)) },
-    "SetCondition":                      func() { gp.script.L.PushGoFunction(setCondition(gp)) },
-    "SetPosition":                       func() { gp.script.L.PushGoFunction(setPosition(gp)) },
-    "SetHp":                             func() { gp.script.L.PushGoFunction(setHp(gp)) },
-    "SetAp":                             func() { gp.script.L.PushGoFunction(setAp(gp)) },
-    "RemoveEnt":                         func() { gp.script.L.PushGoFunction(removeEnt(gp)) },
-    "PlayAnimations":                    func() { gp.script.L.PushGoFunction(playAnimations(gp)) },
-    "PlayMusic":                         func() { gp.script.L.PushGoFunction(playMusic(gp)) },
-    "StopMusic":                         func() { gp.script.L.PushGoFunction(stopMusic(gp)) },
-    "SetMusicParam":                     func() { gp.script.L.PushGoFunction(setMusicParam(gp)) },
-    "PlaySound":                         func() { gp.script.L.PushGoFunction(playSound(gp)) },
-    "SetWaypoint":                       func() { gp.script.L.PushGoFunction(setWaypoint(gp)) },
-    "RemoveWaypoint":                    func() { gp.script.L.PushGoFunction(removeWaypoint(gp)) },
-    "Rand":                              func() { gp.script.L.PushGoFunction(randFunc(gp)) },
-    "Sleep":                             func() { gp.script.L.PushGoFunction(sleepFunc(gp)) },
-    "EndGame":                           func() { gp.script.L.PushGoFunction(endGameFunc(gp)) },
-  })
-  gp.script.L.SetMetaTable(-2)
-  gp.script.L.SetGlobal("Script")
-
-  gp.script.L.NewTable()
-  LuaPushSmartFunctionTable(gp.script.L, FunctionTable{
-    "Active": func() {
-      gp.script.L.PushGoFunction(
-        func(L *lua.State) int {
-          L.PushBoolean(game_key != "")
-          return 1
-        })
-    },
-    "Side":                func() { gp.script.L.PushGoFunction(netSideFunc(gp)) },
-    "UpdateState":         func() { gp.script.L.PushGoFunction(updateStateFunc(gp)) },
-    "UpdateExecs":         func() { gp.script.L.PushGoFunction(updateExecsFunc(gp)) },
-    "Wait":                func() { gp.script.L.PushGoFunction(netWaitFunc(gp)) },
-    "LatestStateAndExecs": func() { gp.script.L.PushGoFunction(netLatestStateAndExecsFunc(gp)) },
-  })
-  gp.script.L.SetMetaTable(-2)
-  gp.script.L.SetGlobal("Net")
-
-  registerUtilityFunctions(gp.script.L)
+  makeGameScript(gp, player, game_key)
+  // Online games and games that aren't being played by anyone can't be
+  // resumed.
+  gp.script.autosave = game_key == "" && gp.input == nil
   if player.Lua_store != nil {
     loadGameStateRaw(gp, gp.script.L, player.Game_state)
     err := LuaDecodeTable(bytes.NewBuffer(player.Lua_store), gp.script.L, gp.game)
@@ -156,13 +100,15 @@ func startGameScript(gp *GamePanel, path string, player *Player, data map[string
   // if resp.Game.Denizens_id == 
   go func() {
     if game_key != "" {
-      var net_id mrgnet.NetId
-      fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
+      net_id := getNetId()
       var req mrgnet.StatusRequest
       req.Game_key = game_key
       req.Id = net_id
       var resp mrgnet.StatusResponse
-      mrgnet.DoAction("status", req, &resp)
+      if err := mrgnet.DoAction("status", req, &resp); err != nil {
+        base.Error().Printf("Unable to get game status: %v", err)
+        return
+      }
       if resp.Err != "" {
         base.Error().Printf("%s", resp.Err)
         return
@@ -268,6 +214,123 @@ func startGameScript(gp *GamePanel, path string, player *Player, data map[string
   }()
 }
 
+// Sets up a new lua state for gp with the Script and Net apis.
+func makeGameScript(gp *GamePanel, player *Player, game_key mrgnet.GameKey) {
+  gp.script = &gameScript{player: player}
+  base.Log().Printf("script = %p", gp.script)
+
+  gp.script.L = lua.NewState()
+  gp.script.L.OpenLibs()
+  gp.script.L.SetExecutionLimit(25000)
+  gp.script.L.NewTable()
+  LuaPushSmartFunctionTable(gp.script.L, FunctionTable{
+    "ChooserFromFile":                   func() { gp.script.L.PushGoFunction(chooserFromFile(gp)) },
+    "StartScript":                       func() { gp.script.L.PushGoFunction(startScript(gp, player)) },
+    "GameOnRound":                       func() { gp.script.L.PushGoFunction(doGameOnRound(gp)) },
+    "SaveGameState":                     func() { gp.script.L.PushGoFunction(saveGameState(gp)) },
+    "LoadGameState":                     func() { gp.script.L.PushGoFunction(loadGameState(gp)) },
+    "DoExec":                            func() { gp.script.L.PushGoFunction(doExec(gp)) },
+    "SelectEnt":                         func() { gp.script.L.PushGoFunction(selectEnt(gp)) },
+    "FocusPos":                          func() { gp.script.L.PushGoFunction(focusPos(gp)) },
+    "FocusZoom":                         func() { gp.script.L.PushGoFunction(focusZoom(gp)) },
+    "SelectHouse":                       func() { gp.script.L.PushGoFunction(selectHouse(gp)) },
+    "LoadHouse":                         func() { gp.script.L.PushGoFunction(loadHouse(gp)) },
+    "SaveStore":                         func() { gp.script.L.PushGoFunction(saveStore(gp, player)) },
+    "ShowMainBar":                       func() { gp.script.L.PushGoFunction(showMainBar(gp, player)) },
+    "SpawnEntityAtPosition":             func() { gp.script.L.PushGoFunction(spawnEntityAtPosition(gp)) },
+    "GetSpawnPointsMatching":            func() { gp.script.L.PushGoFunction(getSpawnPointsMatching(gp)) },
+    "SpawnEntitySomewhereInSpawnPoints": func() { gp.script.L.PushGoFunction(spawnEntitySomewhereInSpawnPoints(gp)) },
+    "IsSpawnPointInLos":                 func() { gp.script.L.PushGoFunction(isSpawnPointInLos(gp)) },
+    "PlaceEntities":                     func() { gp.script.L.PushGoFunction(placeEntities(gp)) },
+    "RoomAtPos":                         func() { gp.script.L.PushGoFunction(roomAtPos(gp)) },
+    "SetLosMode":                        func() { gp.script.L.PushGoFunction(setLosMode(gp)) },
+    "GetAllEnts":                        func() { gp.script.L.PushGoFunction(getAllEnts(gp)) },
+    "DialogBox":                         func() { gp.script.L.PushGoFunction(dialogBox(gp)) },
+    "PickFromN":                         func() { gp.script.L.PushGoFunction(pickFromN(gp)) },
+    "SetGear":                           func() { gp.script.L.PushGoFunction(setGear(gp)) },
+    "BindAi":                            func() { gp.script.L.PushGoFunction(bindAi(gp)) },
+    "SetVisibility":                     func() { gp.script.L.PushGoFunction(setVisibility(gp)) },
+    "EndPlayerInteraction":              func() { gp.script.L.PushGoFunction(endPlayerInteraction(gp)) },
+    "GetLos":                            func() { gp.script.L.PushGoFunction(getLos(gp)) },
+    "SetVisibleSpawnPoints":             func() { gp.script.L.PushGoFunction(setVisibleSpawnPoints(gp)) },
+    "SetCondition":                      func() { gp.script.L.PushGoFunction(setCondition(gp)) },
+    "SetPosition":                       func() { gp.script.L.PushGoFunction(setPosition(gp)) },
+    "SetHp":                             func() { gp.script.L.PushGoFunction(setHp(gp)) },
+    "SetAp":                             func() { gp.script.L.PushGoFunction(setAp(gp)) },
+    "RemoveEnt":                         func() { gp.script.L.PushGoFunction(removeEnt(gp)) },
+    "PlayAnimations":                    func() { gp.script.L.PushGoFunction(playAnimations(gp)) },
+    "PlayMusic":                         func() { gp.script.L.PushGoFunction(playMusic(gp)) },
+    "StopMusic":                         func() { gp.script.L.PushGoFunction(stopMusic(gp)) },
+    "SetMusicParam":                     func() { gp.script.L.PushGoFunction(setMusicParam(gp)) },
+    "PlaySound":                         func() { gp.script.L.PushGoFunction(playSound(gp)) },
+    "SetWaypoint":                       func() { gp.script.L.PushGoFunction(setWaypoint(gp)) },
+    "RemoveWaypoint":                    func() { gp.script.L.PushGoFunction(removeWaypoint(gp)) },
+    "Rand":                              func() { gp.script.L.PushGoFunction(randFunc(gp)) },
+    "Sleep":                             func() { gp.script.L.PushGoFunction(sleepFunc(gp)) },
+    "EndGame":                           func() { gp.script.L.PushGoFunction(endGameFunc(gp)) },
+  })
+  gp.script.L.SetMetaTable(-2)
+  gp.script.L.SetGlobal("Script")
+
+  gp.script.L.NewTable()
+  LuaPushSmartFunctionTable(gp.script.L, FunctionTable{
+    "Active": func() {
+      gp.script.L.PushGoFunction(
+        func(L *lua.State) int {
+          L.PushBoolean(game_key != "")
+          return 1
+        })
+    },
+    "Spectating": func() {
+      gp.script.L.PushGoFunction(
+        func(L *lua.State) int {
+          // The script can ask before it has loaded a house, in which case
+          // there isn't a game to be spectating yet.
+          L.PushBoolean(gp.game != nil && gp.game.net.spectator)
+          return 1
+        })
+    },
+    "Side":                func() { gp.script.L.PushGoFunction(netSideFunc(gp)) },
+    "UpdateState":         func() { gp.script.L.PushGoFunction(updateStateFunc(gp)) },
+    "UpdateExecs":         func() { gp.script.L.PushGoFunction(updateExecsFunc(gp)) },
+    "Wait":                func() { gp.script.L.PushGoFunction(netWaitFunc(gp)) },
+    "LatestStateAndExecs": func() { gp.script.L.PushGoFunction(netLatestStateAndExecsFunc(gp)) },
+    "Forfeit":             func() { gp.script.L.PushGoFunction(netForfeitFunc(gp)) },
+    "CheckSync":           func() { gp.script.L.PushGoFunction(netCheckSyncFunc(gp)) },
+  })
+  gp.script.L.SetMetaTable(-2)
+  gp.script.L.SetGlobal("Net")
+
+  registerUtilityFunctions(gp.script.L)
+  loadCommonScripts(gp.script.L)
+}
+
+// Runs every script in scripts/common so that level scripts can use the
+// functions they define.  These are always read locally, even in online games
+// where the level script itself comes from the server.
+func loadCommonScripts(L *lua.State) {
+  dir := filepath.Join(base.GetDataDir(), "scripts", "common")
+  infos, err := ioutil.ReadDir(dir)
+  if err != nil {
+    base.Warn().Printf("Unable to read common scripts in %s: %v", dir, err)
+    return
+  }
+  for _, info := range infos {
+    if info.IsDir() || filepath.Ext(info.Name()) != ".lua" {
+      continue
+    }
+    path := filepath.Join(dir, info.Name())
+    prog, err := ioutil.ReadFile(path)
+    if err != nil {
+      base.Error().Printf("Unable to load common script %s: %v", path, err)
+      continue
+    }
+    if !L.DoString(string(prog)) {
+      base.Error().Printf("There was an error running common script %s", path)
+    }
+  }
+}
+
 func (gs *gameScript) OnRoundWaiting(g *Game) {
   g.Side = g.net.side
   g.Turn--
@@ -315,11 +378,26 @@ func (gs *gameScript) OnRoundWaiting(g *Game) {
 // Runs RoundEnd
 func (gs *gameScript) OnRound(g *Game) {
   base.Log().Printf("Launching script.RoundStart")
+  if gs.replay != nil {
+    base.Log().Printf("SCRIPT: OnRoundReplaying")
+    gs.OnRoundReplaying(g)
+    return
+  }
+  if g.net.spectator {
+    base.Log().Printf("SCRIPT: OnRoundSpectating")
+    gs.OnRoundSpectating(g)
+    return
+  }
   if (g.Turn%2 == 1) != (g.Side == SideHaunt) {
     base.Log().Printf("SCRIPT: OnRoundWaiting")
     gs.OnRoundWaiting(g)
     return
   }
+  if gs.autosave && g.Turn%2 == 1 {
+    if err := gs.saveToSlot(g, autosave_name, true); err != nil {
+      base.Warn().Printf("Unable to autosave: %v", err)
+    }
+  }
   go func() {
     // // round begins automatically
     // <-round_middle
@@ -471,6 +549,15 @@ func selectHouse(gp *GamePanel) lua.GoFunction {
     }
     gp.script.syncStart()
     defer gp.script.syncEnd()
+    if gp.input != nil {
+      names := gp.input.Choose("SelectHouse", base.GetAllNamesInRegistry("houses"), 1, 1)
+      if len(names) != 1 {
+        base.Error().Printf("Must select exactly one house.")
+        return 0
+      }
+      L.PushString(names[0])
+      return 1
+    }
     selector, output, err := MakeUiSelectMap(gp)
     if err != nil {
       base.Error().Printf("Error selecting map: %v", err)
@@ -489,11 +576,6 @@ func selectHouse(gp *GamePanel) lua.GoFunction {
   }
 }
 
-type totalState struct {
-  Game  **Game
-  Store []byte
-}
-
 func saveGameState(gp *GamePanel) lua.GoFunction {
   return func(L *lua.State) int {
     if !LuaCheckParamsOk(L, "SaveGameState") {
@@ -507,11 +589,7 @@ func saveGameState(gp *GamePanel) lua.GoFunction {
     LuaEncodeValue(buf, L, -1)
     L.Pop(1)
     base.Log().Printf("SaveGameState-1: %d", buf.Len())
-    ts := totalState{
-      Game:  &gp.game,
-      Store: buf.Bytes(),
-    }
-    str, err := base.ToGobToBase64(ts)
+    str, err := encodeGameState(gp.game, buf.Bytes())
     if err != nil {
       base.Error().Printf("Error gobbing game state: %v", err)
       return 0
@@ -542,16 +620,13 @@ func loadGameStateRaw(gp *GamePanel, L *lua.State, state string) {
     viewer = gp.game.viewer
     hv_state = gp.game.viewer.GetState()
   }
-  var ts totalState
-  ts.Game = &gp.game
-  err := base.FromBase64FromGob(&ts, state)
+  store, err := decodeGameState(state, &gp.game)
   if err != nil {
     base.Error().Printf("Error decoding game state: %v", err)
     return
   }
-  // gp.game = ts.Game
   gp.game.script = gp.script
-  LuaDecodeValue(bytes.NewBuffer(ts.Store), L, gp.game)
+  LuaDecodeValue(bytes.NewBuffer(store), L, gp.game)
   if false {
     L.GetGlobal("store")
     // Other side's store on the stack, with our store on top, we're going to
@@ -592,6 +667,22 @@ func loadGameStateRaw(gp *GamePanel, L *lua.State, state string) {
   }
   gp.AnchorBox.AddChild(gp.game.viewer, gui.Anchor{0.5, 0.5, 0.5, 0.5})
   gp.AnchorBox.AddChild(MakeOverlay(gp.game), gui.Anchor{0.5, 0.5, 0.5, 0.5})
+
+  if gp.chat == nil {
+    var err error
+    gp.chat, err = MakeChatPanel(gp)
+    if err != nil {
+      base.Error().Printf("Unable to make chat panel: %v", err)
+    }
+  }
+  if gp.chat != nil {
+    gp.AnchorBox.RemoveChild(gp.chat)
+    gp.AnchorBox.AddChild(gp.chat, gui.Anchor{0, 1, 0, 1})
+  }
+  if gp.replay != nil {
+    gp.AnchorBox.RemoveChild(gp.replay)
+    gp.AnchorBox.AddChild(gp.replay, gui.Anchor{0.5, 0, 0.5, 0})
+  }
 }
 
 func loadGameState(gp *GamePanel) lua.GoFunction {
@@ -708,23 +799,37 @@ func chooserFromFile(gp *GamePanel) lua.GoFunction {
     gp.script.syncStart()
     defer gp.script.syncEnd()
     path := filepath.Join(base.GetDataDir(), L.ToString(-1))
-    chooser, done, err := makeChooserFromOptionBasicsFile(path)
-    if err != nil {
-      base.Error().Printf("Error making chooser: %v", err)
-      return 0
+    var res []string
+    if gp.input != nil {
+      var bops []OptionBasic
+      err := base.LoadAndProcessObject(path, "json", &bops)
+      if err != nil {
+        base.Error().Printf("Error making chooser: %v", err)
+        return 0
+      }
+      var ids []string
+      for _, bop := range bops {
+        ids = append(ids, bop.Id)
+      }
+      res = gp.input.Choose(L.ToString(-1), ids, 1, 1)
+    } else {
+      chooser, done, err := makeChooserFromOptionBasicsFile(path)
+      if err != nil {
+        base.Error().Printf("Error making chooser: %v", err)
+        return 0
+      }
+      gp.AnchorBox.AddChild(chooser, gui.Anchor{0.5, 0.5, 0.5, 0.5})
+      gp.script.syncEnd()
+      res = <-done
+      gp.script.syncStart()
+      gp.AnchorBox.RemoveChild(chooser)
     }
-    gp.AnchorBox.AddChild(chooser, gui.Anchor{0.5, 0.5, 0.5, 0.5})
-    gp.script.syncEnd()
-
-    res := <-done
     L.NewTable()
     for i, s := range res {
       L.PushInteger(i + 1)
       L.PushString(s)
       L.SetTable(-3)
     }
-    gp.script.syncStart()
-    gp.AnchorBox.RemoveChild(chooser)
     return 1
   }
 }
@@ -744,6 +849,9 @@ func loadHouse(gp *GamePanel) lua.GoFunction {
       return 0
     }
     gp.game = makeGame(def)
+    if gp.seed != 0 {
+      gp.game.SeedRand(gp.seed)
+    }
     gp.game.viewer.Edit_mode = true
     gp.game.script = gp.script
     base.Log().Printf("script = %p", gp.game.script)
@@ -947,6 +1055,16 @@ func placeEntities(gp *GamePanel) lua.GoFunction {
       costs = append(costs, L.ToInteger(-1))
       L.Pop(2)
     }
+    if gp.input != nil {
+      ents := gp.input.Place(gp.game, names, costs, L.ToInteger(-2), L.ToInteger(-1), L.ToString(-4))
+      L.NewTable()
+      for i := range ents {
+        L.PushInteger(i + 1)
+        LuaPushEntity(L, ents[i])
+        L.SetTable(-3)
+      }
+      return 1
+    }
     ep, done, err := MakeEntityPlacer(gp.game, names, costs, L.ToInteger(-2), L.ToInteger(-1), L.ToString(-4))
     if err != nil {
       base.Error().Printf("Unable to make entity placer: %v", err)
@@ -1032,22 +1150,13 @@ func dialogBox(gp *GamePanel) lua.GoFunction {
       }
       L.Pop(1)
     }
-    box, output, err := MakeDialogBox(filepath.ToSlash(path), args)
+    choices, err := gp.showDialog(path, args)
     if err != nil {
       base.Error().Printf("Error making dialog: %v", err)
       return 0
     }
-    gp.AnchorBox.AddChild(box, gui.Anchor{0.5, 0.5, 0.5, 0.5})
-    gp.script.syncEnd()
-
-    var choices []string
-    for choice := range output {
-      choices = append(choices, choice)
-    }
     base.Log().Printf("Dialog box press: %v", choices)
 
-    gp.script.syncStart()
-    gp.AnchorBox.RemoveChild(box)
     L.NewTable()
     for i, choice := range choices {
       L.PushInteger(i + 1)
@@ -1058,6 +1167,29 @@ func dialogBox(gp *GamePanel) lua.GoFunction {
   }
 }
 
+// Shows the dialog at path and waits until the player is done with it, then
+// returns the choices they made.  Must be called between syncStart and
+// syncEnd, the game keeps running while the dialog is up.
+func (gp *GamePanel) showDialog(path string, args map[string]string) ([]string, error) {
+  if gp.input != nil {
+    return gp.input.Dialog(filepath.ToSlash(path), args), nil
+  }
+  box, output, err := MakeDialogBox(filepath.ToSlash(path), args)
+  if err != nil {
+    return nil, err
+  }
+  gp.AnchorBox.AddChild(box, gui.Anchor{0.5, 0.5, 0.5, 0.5})
+  gp.script.syncEnd()
+
+  var choices []string
+  for choice := range output {
+    choices = append(choices, choice)
+  }
+  gp.script.syncStart()
+  gp.AnchorBox.RemoveChild(box)
+  return choices, nil
+}
+
 type iconWithText struct {
   Name string
   Icon texture.Object
@@ -1130,6 +1262,15 @@ func pickFromN(gp *GamePanel) lua.GoFunction {
       options = append(options, &option)
       L.Pop(1)
     }
+    if gp.input != nil {
+      L.NewTable()
+      for i, name := range gp.input.Choose("PickFromN", option_names, min, max) {
+        L.PushInteger(i + 1)
+        L.PushString(name)
+        L.SetTable(-3)
+      }
+      return 1
+    }
     var selector hui.Selector
     if min == 1 && max == 1 {
       selector = hui.SelectExactlyOne
@@ -1209,6 +1350,11 @@ func bindAi(gp *GamePanel) lua.GoFunction {
       return 0
     }
     target := L.ToString(-2)
+    if source == "human" && gp.input != nil {
+      if ai := gp.input.HumanAi(target); ai != "" {
+        source = ai
+      }
+    }
     switch target {
     case "denizen":
       switch source {
@@ -1302,17 +1448,11 @@ func saveStore(gp *GamePanel, player *Player) lua.GoFunction {
     }
     gp.script.syncStart()
     defer gp.script.syncEnd()
-    UpdatePlayer(player, gp.script.L)
-    str, err := base.ToGobToBase64(gp.game)
+    // The player goes into the autosave as it is, if the script is about to
+    // start another level then that's where the autosave should pick up.
+    err := gp.script.writeSlot(gp.game, player, autosave_name, true)
     if err != nil {
-      base.Error().Printf("Error gobbing game state: %v", err)
-      return 0
-    }
-    player.Game_state = str
-    player.Name = "autosave"
-    err = SavePlayer(player)
-    if err != nil {
-      base.Warn().Printf("Unable to save player: %v", err)
+      base.Warn().Printf("Unable to autosave: %v", err)
     }
     return 0
   }
@@ -1681,7 +1821,10 @@ func sleepFunc(gp *GamePanel) lua.GoFunction {
       return 0
     }
     seconds := L.ToNumber(-1)
-    time.Sleep(time.Microsecond * time.Duration(1000000*seconds))
+    // Nobody is watching a headless game, so there's nothing to wait for.
+    if gp.input == nil {
+      time.Sleep(time.Microsecond * time.Duration(1000000*seconds))
+    }
     return 1
   }
 }
@@ -1691,6 +1834,12 @@ func endGameFunc(gp *GamePanel) lua.GoFunction {
     if !LuaCheckParamsOk(L, "EndGame") {
       return 0
     }
+    if gp.input != nil {
+      gp.script.syncStart()
+      gp.over = true
+      gp.script.syncEnd()
+      return 0
+    }
     gp.game.Ents = nil
     gp.game.Think(1) // This should clean things up
     Restart()
@@ -1703,14 +1852,23 @@ func netSideFunc(gp *GamePanel) lua.GoFunction {
     if !LuaCheckParamsOk(L, "Side") {
       return 0
     }
+    if gp.game.net.spectator {
+      // Spectators aren't on either side, so they get whichever side they
+      // chose to follow.
+      if gp.game.net.side == SideHaunt {
+        L.PushString("Denizens")
+      } else {
+        L.PushString("Intruders")
+      }
+      return 1
+    }
     if gp.game.net.game == nil {
       // If we haven't gotten the game yet that is because it is the first
       // turn, so it must be the Denizens turn.
       L.PushString("Denizens")
       return 1
     }
-    var net_id mrgnet.NetId
-    fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
+    net_id := getNetId()
     switch {
     case gp.game.net.game.Denizens_id == net_id:
       L.PushString("Denizens")
@@ -1733,10 +1891,13 @@ func updateStateFunc(gp *GamePanel) lua.GoFunction {
       base.Error().Printf("Tried to UpdateState in a non-Net game.")
       return 0
     }
+    if gp.game.net.spectator {
+      base.Error().Printf("Tried to UpdateState while spectating.")
+      return 0
+    }
     gp.script.syncStart()
     defer gp.script.syncEnd()
-    var net_id mrgnet.NetId
-    fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
+    net_id := getNetId()
     var req mrgnet.UpdateGameRequest
     req.Id = net_id
     req.Game_key = gp.game.net.key
@@ -1744,7 +1905,10 @@ func updateStateFunc(gp *GamePanel) lua.GoFunction {
     req.Intruders = gp.game.Side == SideExplorers
     req.Before = []byte(L.ToString(-1))
     var resp mrgnet.UpdateGameResponse
-    mrgnet.DoAction("update", req, &resp)
+    if err := mrgnet.DoAction("update", req, &resp); err != nil {
+      base.Error().Printf("Unable to update game state: %v", err)
+      return 0
+    }
     if resp.Err != "" {
       base.Error().Printf("Error updating game state: %v", resp.Err)
       return 0
@@ -1763,6 +1927,10 @@ func updateExecsFunc(gp *GamePanel) lua.GoFunction {
       base.Error().Printf("Tried to UpdateExecs in a non-Net game.")
       return 0
     }
+    if gp.game.net.spectator {
+      base.Error().Printf("Tried to UpdateExecs while spectating.")
+      return 0
+    }
     buf := bytes.NewBuffer(nil)
     err := LuaEncodeValue(buf, L, -1)
     if err != nil {
@@ -1771,8 +1939,7 @@ func updateExecsFunc(gp *GamePanel) lua.GoFunction {
     }
     gp.script.syncStart()
     defer gp.script.syncEnd()
-    var net_id mrgnet.NetId
-    fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
+    net_id := getNetId()
     var req mrgnet.UpdateGameRequest
     req.Id = net_id
     req.Game_key = gp.game.net.key
@@ -1780,8 +1947,14 @@ func updateExecsFunc(gp *GamePanel) lua.GoFunction {
     req.Intruders = gp.game.Side == SideExplorers
     req.Execs = buf.Bytes()
     req.After = []byte(L.ToString(-2))
+    summary := gp.game.stateSummary()
+    req.Checksum = summaryChecksum(summary)
+    req.Summary = []byte(strings.Join(summary, "\n"))
     var resp mrgnet.UpdateGameResponse
-    mrgnet.DoAction("update", req, &resp)
+    if err := mrgnet.DoAction("update", req, &resp); err != nil {
+      base.Error().Printf("Unable to update game execs: %v", err)
+      return 0
+    }
     if resp.Err != "" {
       base.Error().Printf("Error updating game execs: %v", resp.Err)
       return 0
@@ -1800,33 +1973,17 @@ func netWaitFunc(gp *GamePanel) lua.GoFunction {
       base.Error().Printf("Tried to Wait in a non-net game.")
       return 0
     }
-    var net_id mrgnet.NetId
-    fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
-    var req mrgnet.StatusRequest
-    req.Game_key = gp.game.net.key
-    req.Id = net_id
-    req.Sizes_only = true
-    for {
-      var resp mrgnet.StatusResponse
-      mrgnet.DoAction("status", req, &resp)
-      if resp.Err != "" {
-        base.Error().Printf("%s", resp.Err)
-        return 0
-      }
-      expect := gp.game.Turn + 1
-      if len(resp.Game.Before) == len(resp.Game.Execs) && len(resp.Game.Before) == expect {
-        base.Log().Printf("Found the expected %d states", expect)
-        req.Sizes_only = false
-        mrgnet.DoAction("status", req, &resp)
-        if resp.Err != "" {
-          base.Error().Printf("%s", resp.Err)
-          return 0
-        }
-        break
-      }
-      base.Log().Printf("Found %d instead of %d states", len(resp.Game.Execs), expect)
-      time.Sleep(time.Second * 5)
+    net_id := getNetId()
+    expect := gp.game.Turn + 1
+    game, err := waitForTurns(net_id, gp.game.net.key, expect)
+    if err != nil {
+      base.Error().Printf("Unable to wait for turn %d: %v", expect, err)
+      return 0
     }
+    gp.script.syncStart()
+    gp.game.net.game = game
+    gp.script.syncEnd()
+    base.Log().Printf("Found the expected %d states", expect)
     return 0
   }
 }
@@ -1840,13 +1997,15 @@ func netLatestStateAndExecsFunc(gp *GamePanel) lua.GoFunction {
       base.Error().Printf("Tried to get LatestStateAndExecs in a non-net game.")
       return 0
     }
-    var net_id mrgnet.NetId
-    fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
+    net_id := getNetId()
     var req mrgnet.StatusRequest
     req.Game_key = gp.game.net.key
     req.Id = net_id
     var resp mrgnet.StatusResponse
-    mrgnet.DoAction("status", req, &resp)
+    if err := mrgnet.DoAction("status", req, &resp); err != nil {
+      base.Error().Printf("Unable to get game status: %v", err)
+      return 0
+    }
     if resp.Err != "" {
       base.Error().Printf("%s", resp.Err)
       return 0
@@ -1859,12 +2018,77 @@ func netLatestStateAndExecsFunc(gp *GamePanel) lua.GoFunction {
     L.PushString(string(state))
     buf := bytes.NewBuffer(resp.Game.Execs[len(resp.Game.Execs)-1])
     gp.script.syncStart()
+    gp.game.net.game = resp.Game
     LuaDecodeValue(buf, L, gp.game)
     gp.script.syncEnd()
     return 2
   }
 }
 
+// Should be called after playing back the execs from LatestStateAndExecs,
+// returns false if we didn't end up in the same state that the other player
+// did.  What differs is logged, and the player is told about it.
+func netCheckSyncFunc(gp *GamePanel) lua.GoFunction {
+  return func(L *lua.State) int {
+    if !LuaCheckParamsOk(L, "CheckSync") {
+      return 0
+    }
+    if gp.game.net.key == "" {
+      base.Error().Printf("Tried to CheckSync in a non-net game.")
+      return 0
+    }
+    gp.script.syncStart()
+    in_sync := gp.game.net.game == nil || gp.game.checkSync(gp.game.net.game, len(gp.game.net.game.Execs)-1)
+    if !in_sync {
+      args := map[string]string{"turn": fmt.Sprintf("%d", len(gp.game.net.game.Execs))}
+      if _, err := gp.showDialog(desync_dialog, args); err != nil {
+        base.Error().Printf("Unable to show the desync dialog: %v", err)
+      }
+    }
+    gp.script.syncEnd()
+    L.PushBoolean(in_sync)
+    return 1
+  }
+}
+
+// Returns nil unless the game was ended because a player missed their turn
+// deadline, in which case it returns the winning side, either "Denizens" or
+// "Intruders".
+func netForfeitFunc(gp *GamePanel) lua.GoFunction {
+  return func(L *lua.State) int {
+    if !LuaCheckParamsOk(L, "Forfeit") {
+      return 0
+    }
+    if gp.game.net.key == "" {
+      base.Error().Printf("Tried to check for a Forfeit in a non-net game.")
+      return 0
+    }
+    var req mrgnet.StatusRequest
+    req.Game_key = gp.game.net.key
+    req.Id = getNetId()
+    req.Sizes_only = true
+    var resp mrgnet.StatusResponse
+    if err := mrgnet.DoAction("status", req, &resp); err != nil {
+      base.Error().Printf("Unable to get game status: %v", err)
+      return 0
+    }
+    if resp.Err != "" || resp.Game == nil {
+      base.Error().Printf("Unable to get game status: %s", resp.Err)
+      return 0
+    }
+    if !resp.Game.Forfeit {
+      L.PushNil()
+      return 1
+    }
+    if resp.Game.Winner == resp.Game.Denizens_id {
+      L.PushString("Denizens")
+    } else {
+      L.PushString("Intruders")
+    }
+    return 1
+  }
+}
+
 // Ripped from game/ai/ai.go - should probably sync up with it
 func registerUtilityFunctions(L *lua.State) {
   L.Register("print", func(L *lua.State) int {
@@ -0,0 +1,152 @@
+package game
+
+import (
+  "bytes"
+  "errors"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// Makes a GamePanel that watches an online game without playing in it.  The
+// Before and Execs of every turn are replayed as they arrive on the server,
+// starting from the first turn, and nothing is ever sent back.  follow is
+// the side whose visibility the spectator sees.
+func MakeSpectatorPanel(game_key mrgnet.GameKey, follow Side) *GamePanel {
+  var gp GamePanel
+  gp.AnchorBox = gui.MakeAnchorBox(gui.Dims{1024, 768})
+  startSpectatorScript(&gp, game_key, follow)
+  return &gp
+}
+
+func startSpectatorScript(gp *GamePanel, game_key mrgnet.GameKey, follow Side) {
+  base.Log().Printf("startSpectatorScript")
+  makeGameScript(gp, &Player{}, game_key)
+  gp.script.L.NewTable()
+  gp.script.L.SetGlobal("store")
+  gp.script.sync = make(chan struct{})
+
+  go func() {
+    game, err := spectatorTurn(game_key, 0)
+    if err != nil {
+      base.Error().Printf("Unable to spectate game %s: %v", game_key, err)
+      return
+    }
+    if game.Script == nil {
+      base.Error().Printf("Game %s doesn't have a script yet.", game_key)
+      return
+    }
+    if !gp.script.L.DoString(string(game.Script)) {
+      base.Error().Printf("There was an error running the script for game %s.", game_key)
+      return
+    }
+    loadGameStateRaw(gp, gp.script.L, string(game.Before[0]))
+    if gp.game == nil {
+      base.Error().Printf("Unable to load the first turn of game %s.", game_key)
+      return
+    }
+    gp.game.net.key = game_key
+    gp.game.net.game = game
+    gp.game.net.side = follow
+    gp.game.net.spectator = true
+    gp.script.L.DoString("OnStartup()")
+    gp.game.SetVisibility(follow)
+    gp.game.silenceAis()
+    gp.game.comm.script_to_game <- nil
+  }()
+}
+
+// Waits until the specified turn, counting from zero, is on the server and
+// returns the game.  Returns an error if the game ended before then.
+func spectatorTurn(key mrgnet.GameKey, turn int) (*mrgnet.Game, error) {
+  net_id := getNetId()
+  _, err := waitForTurns(net_id, key, turn+1)
+  if err != nil {
+    return nil, err
+  }
+  var resp mrgnet.StatusResponse
+  err = mrgnet.DoAction("status", mrgnet.StatusRequest{Id: net_id, Game_key: key}, &resp)
+  if err != nil {
+    return nil, err
+  }
+  if resp.Err != "" {
+    return nil, errors.New(resp.Err)
+  }
+  if resp.Game == nil {
+    return nil, errors.New("Server didn't send the game.")
+  }
+  if len(resp.Game.Before) <= turn || len(resp.Game.Execs) <= turn {
+    return nil, errors.New("The game is over.")
+  }
+  return resp.Game, nil
+}
+
+// Spectators don't run any Ais, everything that the Ais did is already in
+// the execs that we replay.
+func (g *Game) silenceAis() {
+  for _, ai := range []*Ai{&g.Ai.minions, &g.Ai.denizens, &g.Ai.intruders} {
+    if *ai != nil {
+      (*ai).Terminate()
+    }
+    *ai = inactiveAi{}
+  }
+  for _, ent := range g.Ents {
+    if ent.Ai != nil {
+      ent.Ai.Terminate()
+    }
+    ent.Ai = inactiveAi{}
+  }
+  g.player_inactive = true
+}
+
+// Spectators never get a turn.  Instead the main phase ends immediately and
+// the next turn is replayed, with the script's DoPlayback() if it has one.
+func (gs *gameScript) OnRoundSpectating(g *Game) {
+  go func() {
+    // signals to the game that we're done with the startup stuff
+    g.comm.script_to_game <- nil
+
+    _exec := <-g.comm.game_to_script
+    if _exec != nil {
+      panic("Got an exec when we shouldn't have gotten one.")
+    }
+
+    game, err := spectatorTurn(g.net.key, gs.spectated)
+    if err != nil {
+      base.Log().Printf("Done spectating game %s: %v", g.net.key, err)
+      return
+    }
+    gs.spectated++
+
+    gs.syncStart()
+    g.net.game = game
+    gs.L.PushString(string(game.Before[gs.spectated-1]))
+    gs.L.SetGlobal("__state")
+    err = LuaDecodeValue(bytes.NewBuffer(game.Execs[gs.spectated-1]), gs.L, g)
+    gs.syncEnd()
+    if err != nil {
+      base.Error().Printf("Unable to decode execs for turn %d: %v", gs.spectated, err)
+      return
+    }
+    gs.L.SetGlobal("__execs")
+
+    gs.L.SetExecutionLimit(250000)
+    gs.L.GetGlobal("DoPlayback")
+    has_playback := !gs.L.IsNil(-1)
+    gs.L.Pop(1)
+    if has_playback {
+      gs.L.DoString("DoPlayback(__state, __execs)")
+    } else {
+      gs.L.DoString("Script.LoadGameState(__state)\nfor _, exec in pairs(__execs) do Script.DoExec(exec) end")
+    }
+
+    gs.syncStart()
+    g.checkSync(game, gs.spectated-1)
+    g.SetVisibility(g.net.side)
+    g.silenceAis()
+    gs.syncEnd()
+
+    g.comm.script_to_game <- nil
+    g.comm.script_to_game <- nil
+  }()
+}
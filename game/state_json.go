@@ -0,0 +1,622 @@
+package game
+
+import (
+  "bytes"
+  "encoding/binary"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game/status"
+  "github.com/MobRulesGames/haunts/house"
+  "io"
+  "sort"
+)
+
+// A human readable copy of everything about a game that changes as it is
+// played, so that a broken game can be looked at and fixed by hand.  It can
+// be exported from a running game or from a player's saved game, edited, and
+// imported back into either one.  The house itself and the PRNGs are not
+// part of it, and are left alone on import.
+type JsonState struct {
+  // The save version and the house this was exported from, only for
+  // reference, these are ignored on import.
+  Version int
+  House   string
+
+  Turn int
+  Side string
+
+  // Patterns of the spawn points that grant los to each side.
+  Los_spawns struct {
+    Denizens, Intruders string
+  }
+
+  // Los modes aren't part of a save, the level's script sets them again when
+  // a saved game is resumed, so these only mean something when they were
+  // exported from a running game.
+  Los_modes struct {
+    Denizens, Intruders JsonLosMode
+  }
+
+  Doors     []JsonDoor
+  Waypoints []JsonWaypoint
+  Ents      []JsonEntity
+
+  // The script's store.  Tables whose keys are all strings are objects, any
+  // other table is an array of [key, value] pairs, and entities are objects
+  // of the form {"__entity": id}.
+  Store interface{}
+}
+
+type JsonLosMode struct {
+  // One of the modes that Script.SetLosMode takes, the mode is left alone on
+  // import if this is empty.
+  Mode string
+
+  // Indices into the rooms of the first floor, only used by "rooms".
+  Rooms []int `json:",omitempty"`
+}
+
+// Every door is listed once, opening or closing it does the same to the
+// matching door in the room on the other side.
+type JsonDoor struct {
+  Room, Door int
+  Name       string
+  Opened     bool
+}
+
+type JsonWaypoint struct {
+  Name   string
+  Side   string
+  X, Y   float64
+  Radius float64
+}
+
+// Entities are matched up by Id on import.  Entities that aren't listed are
+// removed from the game, and entities that aren't in the game are made from
+// the definition called Name.
+type JsonEntity struct {
+  Id   EntityId
+  Name string
+
+  // Only for reference, an entity's side comes from its definition.
+  Side string
+
+  X, Y   float64
+  Active bool
+
+  // Only explorers have gear, and only denizens and intruders have stats.
+  Gear  string           `json:",omitempty"`
+  Stats *status.Snapshot `json:",omitempty"`
+}
+
+var side_names = map[Side]string{
+  SideNone:      "None",
+  SideExplorers: "Intruders",
+  SideHaunt:     "Denizens",
+  SideNpc:       "Npc",
+  SideObject:    "Object",
+}
+
+func sideName(side Side) string {
+  if name, ok := side_names[side]; ok {
+    return name
+  }
+  return fmt.Sprintf("Side %d", side)
+}
+
+func sideFromName(name string) (Side, error) {
+  for side, side_name := range side_names {
+    if side_name == name {
+      return side, nil
+    }
+  }
+  return SideNone, fmt.Errorf("'%s' is not a side.", name)
+}
+
+// The same names that Script.SetLosMode takes.
+var los_mode_names = map[LosMode]string{
+  LosModeNone:     "none",
+  LosModeBlind:    "blind",
+  LosModeAll:      "all",
+  LosModeEntities: "entities",
+  LosModeRooms:    "rooms",
+}
+
+func (g *Game) jsonLosMode(data *sideLosData) JsonLosMode {
+  jlm := JsonLosMode{Mode: los_mode_names[data.mode]}
+  if data.mode != LosModeRooms {
+    return jlm
+  }
+  for _, room := range data.rooms {
+    for i, other := range g.House.Floors[0].Rooms {
+      if room == other {
+        jlm.Rooms = append(jlm.Rooms, i)
+      }
+    }
+  }
+  return jlm
+}
+
+// store is the script's store, as encoded by LuaEncodeValue.
+func MakeJsonState(g *Game, store []byte) (*JsonState, error) {
+  var js JsonState
+  js.Version = Save_version
+  js.House = g.House.Name
+  js.Turn = g.Turn
+  js.Side = sideName(g.Side)
+  js.Los_spawns.Denizens = g.Los_spawns.Denizens.Pattern
+  js.Los_spawns.Intruders = g.Los_spawns.Intruders.Pattern
+  js.Los_modes.Denizens = g.jsonLosMode(&g.los.denizens)
+  js.Los_modes.Intruders = g.jsonLosMode(&g.los.intruders)
+
+  floor := g.House.Floors[0]
+  listed := make(map[*house.Door]bool)
+  for i, room := range floor.Rooms {
+    for j, door := range room.Doors {
+      if listed[door] {
+        continue
+      }
+      if _, other := floor.FindMatchingDoor(room, door); other != nil {
+        listed[other] = true
+      }
+      js.Doors = append(js.Doors, JsonDoor{Room: i, Door: j, Name: door.Defname, Opened: door.Opened})
+    }
+  }
+
+  for _, wp := range g.Waypoints {
+    js.Waypoints = append(js.Waypoints, JsonWaypoint{
+      Name:   wp.Name,
+      Side:   sideName(wp.Side),
+      X:      wp.X,
+      Y:      wp.Y,
+      Radius: wp.Radius,
+    })
+  }
+
+  for _, ent := range g.Ents {
+    je := JsonEntity{
+      Id:     ent.Id,
+      Name:   ent.Defname,
+      Side:   sideName(ent.Side()),
+      X:      ent.X,
+      Y:      ent.Y,
+      Active: ent.Active,
+    }
+    if ent.ExplorerEnt != nil && ent.ExplorerEnt.Gear != nil {
+      je.Gear = ent.ExplorerEnt.Gear.Defname
+    }
+    if ent.Stats != nil {
+      snap := ent.Stats.Snapshot()
+      je.Stats = &snap
+    }
+    js.Ents = append(js.Ents, je)
+  }
+
+  if len(store) > 0 {
+    var err error
+    js.Store, err = storeToJson(bytes.NewBuffer(store))
+    if err != nil {
+      return nil, err
+    }
+  }
+  return &js, nil
+}
+
+func (js *JsonState) losMode(jlm JsonLosMode, rooms []*house.Room) (LosMode, []*house.Room, error) {
+  if jlm.Mode == "" {
+    return LosModeNone, nil, nil
+  }
+  for mode, name := range los_mode_names {
+    if name != jlm.Mode {
+      continue
+    }
+    var mode_rooms []*house.Room
+    for _, index := range jlm.Rooms {
+      if index < 0 || index >= len(rooms) {
+        return mode, nil, fmt.Errorf("There is no room %d.", index)
+      }
+      mode_rooms = append(mode_rooms, rooms[index])
+    }
+    return mode, mode_rooms, nil
+  }
+  return LosModeNone, nil, fmt.Errorf("'%s' is not a los mode.", jlm.Mode)
+}
+
+// Sets g to the state in js and returns the store, encoded by
+// LuaEncodeValue, for the caller to hand to the script.  Nothing is changed
+// if js can't be applied to g.
+func (js *JsonState) Apply(g *Game) ([]byte, error) {
+  // Everything that can fail is checked before anything is changed.
+  side, err := sideFromName(js.Side)
+  if err != nil {
+    return nil, err
+  }
+  floor := g.House.Floors[0]
+  denizens_mode, denizens_rooms, err := js.losMode(js.Los_modes.Denizens, floor.Rooms)
+  if err != nil {
+    return nil, err
+  }
+  intruders_mode, intruders_rooms, err := js.losMode(js.Los_modes.Intruders, floor.Rooms)
+  if err != nil {
+    return nil, err
+  }
+  for _, jd := range js.Doors {
+    if jd.Room < 0 || jd.Room >= len(floor.Rooms) || jd.Door < 0 || jd.Door >= len(floor.Rooms[jd.Room].Doors) {
+      return nil, fmt.Errorf("There is no door %d in room %d.", jd.Door, jd.Room)
+    }
+  }
+  var waypoints []waypoint
+  for _, jw := range js.Waypoints {
+    wp_side, err := sideFromName(jw.Side)
+    if err != nil {
+      return nil, err
+    }
+    waypoints = append(waypoints, waypoint{Name: jw.Name, Side: wp_side, X: jw.X, Y: jw.Y, Radius: jw.Radius})
+  }
+  ent_names := make(map[string]bool)
+  for _, name := range base.GetAllNamesInRegistry("entities") {
+    ent_names[name] = true
+  }
+  listed := make(map[EntityId]bool)
+  for _, je := range js.Ents {
+    if listed[je.Id] {
+      return nil, fmt.Errorf("Entity %d is listed more than once.", je.Id)
+    }
+    listed[je.Id] = true
+    if g.EntityById(je.Id) == nil && !ent_names[je.Name] {
+      return nil, fmt.Errorf("There is no entity named '%s'.", je.Name)
+    }
+  }
+  // The store is always a table, even if it was left out.
+  store_val := js.Store
+  if store_val == nil {
+    store_val = map[string]interface{}{}
+  }
+  store := bytes.NewBuffer(nil)
+  if err := storeFromJson(store, store_val); err != nil {
+    return nil, err
+  }
+
+  g.Turn = js.Turn
+  g.Side = side
+  g.Los_spawns.Denizens.Pattern = js.Los_spawns.Denizens
+  g.Los_spawns.Intruders.Pattern = js.Los_spawns.Intruders
+  g.Waypoints = waypoints
+
+  for _, jd := range js.Doors {
+    room := floor.Rooms[jd.Room]
+    door := room.Doors[jd.Door]
+    door.SetOpened(jd.Opened)
+    if _, other := floor.FindMatchingDoor(room, door); other != nil {
+      other.SetOpened(jd.Opened)
+    }
+  }
+
+  var ents []*Entity
+  for _, ent := range g.Ents {
+    if listed[ent.Id] {
+      ents = append(ents, ent)
+    } else {
+      g.viewer.RemoveDrawable(ent)
+    }
+  }
+  g.Ents = ents
+  for _, je := range js.Ents {
+    ent := g.EntityById(je.Id)
+    if ent == nil {
+      ent = MakeEntity(je.Name, g)
+      ent.Id = je.Id
+      if g.Entity_id <= je.Id {
+        g.Entity_id = je.Id + 1
+      }
+      g.Ents = append(g.Ents, ent)
+    }
+    ent.X, ent.Y = je.X, je.Y
+    ent.Active = je.Active
+    if ent.ExplorerEnt != nil {
+      gear := ""
+      if ent.ExplorerEnt.Gear != nil {
+        gear = ent.ExplorerEnt.Gear.Defname
+      }
+      if gear != je.Gear {
+        if gear != "" {
+          ent.SetGear("")
+        }
+        if je.Gear != "" {
+          ent.SetGear(je.Gear)
+        }
+      }
+    }
+    if ent.Stats != nil && je.Stats != nil {
+      *ent.Stats = status.MakeInstFromSnapshot(*je.Stats)
+    }
+  }
+  g.RecalcLos()
+
+  if js.Los_modes.Denizens.Mode != "" {
+    g.SetLosMode(SideHaunt, denizens_mode, denizens_rooms)
+  }
+  if js.Los_modes.Intruders.Mode != "" {
+    g.SetLosMode(SideExplorers, intruders_mode, intruders_rooms)
+  }
+
+  return store.Bytes(), nil
+}
+
+// Exports the game saved in p.
+func ExportPlayerState(p *Player) (*JsonState, error) {
+  if p.Game_state == "" {
+    return nil, errors.New("This player doesn't have a game in progress.")
+  }
+  var g *Game
+  store, err := decodeGameState(p.Game_state, &g)
+  if err != nil {
+    return nil, err
+  }
+  defer releaseGame(g)
+  return MakeJsonState(g, store)
+}
+
+// Applies js to the game saved in p, and saves the result back into p.
+func ImportPlayerState(p *Player, js *JsonState) error {
+  if p.Game_state == "" {
+    return errors.New("This player doesn't have a game in progress.")
+  }
+  var g *Game
+  if _, err := decodeGameState(p.Game_state, &g); err != nil {
+    return err
+  }
+  defer releaseGame(g)
+  store, err := js.Apply(g)
+  if err != nil {
+    return err
+  }
+  state, err := encodeGameState(g, store)
+  if err != nil {
+    return err
+  }
+  p.Game_state = state
+
+  // The player's Lua_store is loaded on top of the store in the game state
+  // when the game is resumed, so it has to match.
+  var le luaEncodable
+  buf := bytes.NewBuffer(store)
+  if binary.Read(buf, binary.LittleEndian, &le) == nil && le == luaEncTable {
+    p.Lua_store = buf.Bytes()
+  }
+  return nil
+}
+
+// Writes js as indented json.
+func (js *JsonState) Write(w io.Writer) error {
+  data, err := json.MarshalIndent(js, "", "  ")
+  if err != nil {
+    return err
+  }
+  _, err = w.Write(append(data, '\n'))
+  return err
+}
+
+func ReadJsonState(r io.Reader) (*JsonState, error) {
+  var js JsonState
+  if err := json.NewDecoder(r).Decode(&js); err != nil {
+    return nil, err
+  }
+  return &js, nil
+}
+
+// Stops the Ais of a game that isn't going to be played any more and
+// releases its ents.
+func releaseGame(g *Game) {
+  g.Ents = nil
+  g.Think(1) // This should clean things up
+  for _, ai := range []Ai{g.Ai.minions, g.Ai.denizens, g.Ai.intruders} {
+    if ai != nil {
+      ai.Terminate()
+    }
+  }
+}
+
+const json_entity_key = "__entity"
+
+// Reads a value encoded by LuaEncodeValue and returns it in the form that
+// JsonState.Store describes.
+func storeToJson(r io.Reader) (interface{}, error) {
+  var le luaEncodable
+  if err := binary.Read(r, binary.LittleEndian, &le); err != nil {
+    return nil, err
+  }
+  switch le {
+  case luaEncBool:
+    var v byte
+    err := binary.Read(r, binary.LittleEndian, &v)
+    return v == 1, err
+
+  case luaEncNumber:
+    var f float64
+    err := binary.Read(r, binary.LittleEndian, &f)
+    return f, err
+
+  case luaEncNil:
+    return nil, nil
+
+  case luaEncEntity:
+    var id uint64
+    err := binary.Read(r, binary.LittleEndian, &id)
+    return map[string]interface{}{json_entity_key: float64(id)}, err
+
+  case luaEncString:
+    var length uint32
+    if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+      return nil, err
+    }
+    sb := make([]byte, length)
+    err := binary.Read(r, binary.LittleEndian, &sb)
+    return string(sb), err
+
+  case luaEncTable:
+    var pairs [][2]interface{}
+    all_strings := true
+    for {
+      var cont byte
+      if err := binary.Read(r, binary.LittleEndian, &cont); err != nil {
+        return nil, err
+      }
+      if cont == 0 {
+        break
+      }
+      var pair [2]interface{}
+      for i := range pair {
+        v, err := storeToJson(r)
+        if err != nil {
+          return nil, err
+        }
+        pair[i] = v
+      }
+      if _, ok := pair[0].(string); !ok {
+        all_strings = false
+      }
+      pairs = append(pairs, pair)
+    }
+    if !all_strings {
+      return pairs, nil
+    }
+    table := make(map[string]interface{})
+    for _, pair := range pairs {
+      table[pair[0].(string)] = pair[1]
+    }
+    return table, nil
+  }
+  return nil, fmt.Errorf("Unknown lua value id == %d.", le)
+}
+
+// Writes v, in the form that JsonState.Store describes, the same way that
+// LuaEncodeValue would have.
+func storeFromJson(w io.Writer, v interface{}) error {
+  write := func(vals ...interface{}) error {
+    for _, val := range vals {
+      if err := binary.Write(w, binary.LittleEndian, val); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+  switch v := v.(type) {
+  case bool:
+    var b byte
+    if v {
+      b = 1
+    }
+    return write(luaEncBool, b)
+
+  case float64:
+    return write(luaEncNumber, v)
+
+  case nil:
+    return write(luaEncNil)
+
+  case string:
+    return write(luaEncString, uint32(len(v)), []byte(v))
+
+  case map[string]interface{}:
+    if id, ok := v[json_entity_key].(float64); ok && len(v) == 1 {
+      return write(luaEncEntity, uint64(id))
+    }
+    if err := write(luaEncTable); err != nil {
+      return err
+    }
+    var keys []string
+    for key := range v {
+      keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+      if err := write(byte(1)); err != nil {
+        return err
+      }
+      if err := storeFromJson(w, key); err != nil {
+        return err
+      }
+      if err := storeFromJson(w, v[key]); err != nil {
+        return err
+      }
+    }
+    return write(byte(0))
+
+  case []interface{}:
+    if err := write(luaEncTable); err != nil {
+      return err
+    }
+    for _, p := range v {
+      pair, ok := p.([]interface{})
+      if !ok || len(pair) != 2 {
+        return fmt.Errorf("Expected a [key, value] pair, not %v.", p)
+      }
+      if pair[0] == nil {
+        return errors.New("A table can't have a nil key.")
+      }
+      if err := write(byte(1)); err != nil {
+        return err
+      }
+      for _, val := range pair {
+        if err := storeFromJson(w, val); err != nil {
+          return err
+        }
+      }
+    }
+    return write(byte(0))
+
+  case [][2]interface{}:
+    // What storeToJson makes, rather than what comes from json.
+    pairs := make([]interface{}, len(v))
+    for i := range v {
+      pairs[i] = []interface{}{v[i][0], v[i][1]}
+    }
+    return storeFromJson(w, pairs)
+  }
+  return fmt.Errorf("Cannot encode %v in the store.", v)
+}
+
+// Writes the game in progress, and its script's store, to w as a JsonState.
+func (gp *GamePanel) ExportState(w io.Writer) error {
+  if gp.game == nil || gp.game.House == nil || gp.script == nil {
+    return errors.New("There is no game in progress.")
+  }
+  buf := bytes.NewBuffer(nil)
+  L := gp.script.L
+  L.GetGlobal("store")
+  err := LuaEncodeValue(buf, L, -1)
+  L.Pop(1)
+  if err != nil {
+    return err
+  }
+  js, err := MakeJsonState(gp.game, buf.Bytes())
+  if err != nil {
+    return err
+  }
+  return js.Write(w)
+}
+
+// Reads a JsonState from r and applies it to the game in progress, and its
+// script's store.
+func (gp *GamePanel) ImportState(r io.Reader) error {
+  if gp.game == nil || gp.game.House == nil || gp.script == nil {
+    return errors.New("There is no game in progress.")
+  }
+  js, err := ReadJsonState(r)
+  if err != nil {
+    return err
+  }
+  store, err := js.Apply(gp.game)
+  if err != nil {
+    return err
+  }
+  L := gp.script.L
+  if err := LuaDecodeValue(bytes.NewBuffer(store), L, gp.game); err != nil {
+    return err
+  }
+  L.SetGlobal("store")
+  return nil
+}
@@ -0,0 +1,81 @@
+package game_test
+
+import (
+  "bytes"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/game/status"
+
+  // The entities in data_test use the actions defined here.
+  _ "github.com/MobRulesGames/haunts/game/actions"
+)
+
+// Writes js as json and reads it back, the same way mrgstate does.
+func throughJson(c gospec.Context, js *game.JsonState) *game.JsonState {
+  buf := bytes.NewBuffer(nil)
+  c.Assume(js.Write(buf), Equals, nil)
+  js2, err := game.ReadJsonState(buf)
+  c.Assume(err, Equals, nil)
+  return js2
+}
+
+func jsonText(c gospec.Context, js *game.JsonState) string {
+  buf := bytes.NewBuffer(nil)
+  c.Assume(js.Write(buf), Equals, nil)
+  return buf.String()
+}
+
+func JsonStateSpec(c gospec.Context) {
+  loadTestRegistries()
+  s, err := game.MakeScenario("test.lua", nil, 1)
+  c.Assume(err, Equals, nil)
+  defer s.Close()
+  before, err := s.SaveGameState()
+  c.Assume(err, Equals, nil)
+
+  // Everything that an export covers changes between before and after.
+  c.Assume(s.EndTurn(), Equals, nil)
+  ent := s.Ent("Test Denizen")
+  c.Assume(ent, Not(IsNil))
+  ent.Stats.ApplyDamage(0, -1, status.Unspecified)
+  ent.X = 5
+  after, err := s.SaveGameState()
+  c.Assume(err, Equals, nil)
+
+  want, want_store, err := game.DecodeGameState(string(after))
+  c.Assume(err, Equals, nil)
+  c.Assume(len(game.DiffSummaries(want.StateSummary(), mustDecode(c, before).StateSummary())) > 0, Equals, true)
+
+  c.Specify("A game exported and imported into another game ends up the same.", func() {
+    js, err := game.MakeJsonState(want, want_store)
+    c.Assume(err, Equals, nil)
+    g := mustDecode(c, before)
+    store, err := throughJson(c, js).Apply(g)
+    c.Assume(err, Equals, nil)
+    c.Expect(game.DiffSummaries(g.StateSummary(), want.StateSummary()), ContainsExactly, Values())
+    c.Expect(g.Turn, Equals, want.Turn)
+    c.Expect(g.Side, Equals, want.Side)
+    c.Expect(string(store), Equals, string(want_store))
+  })
+
+  c.Specify("A player's game exported and imported into another player ends up the same.", func() {
+    p := &game.Player{Game_state: string(after)}
+    js, err := game.ExportPlayerState(p)
+    c.Assume(err, Equals, nil)
+    p2 := &game.Player{Game_state: string(before)}
+    c.Assume(game.ImportPlayerState(p2, throughJson(c, js)), Equals, nil)
+    js2, err := game.ExportPlayerState(p2)
+    c.Assume(err, Equals, nil)
+    c.Expect(jsonText(c, js2), Equals, jsonText(c, js))
+    g := mustDecode(c, []byte(p2.Game_state))
+    c.Expect(game.DiffSummaries(g.StateSummary(), want.StateSummary()), ContainsExactly, Values())
+    c.Expect(len(p2.Lua_store) > 0, Equals, true)
+  })
+}
+
+func mustDecode(c gospec.Context, state []byte) *game.Game {
+  g, _, err := game.DecodeGameState(string(state))
+  c.Assume(err, Equals, nil)
+  return g
+}
@@ -154,4 +154,28 @@ func ConditionsSpec(c gospec.Context) {
     s.OnRound()
     c.Expect(s.HpCur(), Equals, 75)
   })
+
+  c.Specify("Snapshots keep stats, conditions and how long they've been going.", func() {
+    s := status.MakeInst(status.Base{Hp_max: 100, Ap_max: 10})
+    s.OnBegin()
+    s.ApplyCondition(status.MakeCondition("Fire Debuff Attack"))
+    s.OnRound()
+    s.SetHp(42)
+
+    snap := s.Snapshot()
+    c.Expect(snap.Hp, Equals, 42)
+    c.Assume(len(snap.Conditions), Equals, 1)
+    c.Expect(snap.Conditions[0].Name, Equals, "Fire Debuff Attack")
+
+    s2 := status.MakeInstFromSnapshot(snap)
+    c.Expect(s2.HpCur(), Equals, 42)
+    c.Expect(s2.ApCur(), Equals, s.ApCur())
+    c.Expect(s2.AttackBonusWith(status.Unspecified), Equals, s.AttackBonusWith(status.Unspecified))
+    c.Expect(s2.Snapshot().Conditions[0].Time, Equals, snap.Conditions[0].Time)
+    for i := 0; i < 5; i++ {
+      s.OnRound()
+      s2.OnRound()
+      c.Expect(s2.HpCur(), Equals, s.HpCur())
+    }
+  })
 }
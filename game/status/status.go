@@ -228,11 +228,17 @@ func (s *Inst) SetAp(ap int) {
   s.inst.Dynamic.Ap = ap
 }
 
-func (s *Inst) ApplyDamage(dap, dhp int, kind Kind) {
-  dmg := Damage{Dynamic: Dynamic{Ap: dap, Hp: dhp}, Kind: kind}
+// Returns dmg as it would be after every condition had a chance to modify
+// it, this is what ApplyDamage applies.
+func (s Inst) ModifiedDamage(dmg Damage) Damage {
   for _, c := range s.inst.Conditions {
     dmg = c.ModifyDamage(dmg)
   }
+  return dmg
+}
+
+func (s *Inst) ApplyDamage(dap, dhp int, kind Kind) {
+  dmg := s.ModifiedDamage(Damage{Dynamic: Dynamic{Ap: dap, Hp: dhp}, Kind: kind})
   s.inst.Dynamic.Ap += dmg.Dynamic.Ap
   s.inst.Dynamic.Hp += dmg.Dynamic.Hp
 }
@@ -285,6 +291,52 @@ func (s *Inst) OnRound() {
   s.inst.Conditions = s.inst.Conditions[0 : len(s.inst.Conditions)-num_complete]
 }
 
+// A copy of an Inst with its conditions referred to by name, so that it can
+// be read and edited by people.  See Inst.Snapshot and MakeInstFromSnapshot.
+type Snapshot struct {
+  Base       Base
+  Hp, Ap     int
+  Conditions []ConditionSnapshot
+}
+
+type ConditionSnapshot struct {
+  Name string
+
+  // How many rounds the condition has been in effect, only basic conditions
+  // keep track of this.
+  Time int
+}
+
+func (s Inst) Snapshot() Snapshot {
+  snap := Snapshot{
+    Base: s.inst.Base,
+    Hp:   s.inst.Dynamic.Hp,
+    Ap:   s.inst.Dynamic.Ap,
+  }
+  for _, c := range s.inst.Conditions {
+    cs := ConditionSnapshot{Name: c.Name()}
+    if bc, ok := c.(*BasicCondition); ok {
+      cs.Name = bc.Defname
+      cs.Time = bc.Time
+    }
+    snap.Conditions = append(snap.Conditions, cs)
+  }
+  return snap
+}
+
+func MakeInstFromSnapshot(snap Snapshot) Inst {
+  i := MakeInst(snap.Base)
+  i.inst.Dynamic = Dynamic{Hp: snap.Hp, Ap: snap.Ap}
+  for _, cs := range snap.Conditions {
+    c := MakeCondition(cs.Name)
+    if bc, ok := c.(*BasicCondition); ok {
+      bc.Time = cs.Time
+    }
+    i.ApplyCondition(c)
+  }
+  return i
+}
+
 // Encoding routines - only support json and gob right now
 
 func (si Inst) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,205 @@
+package game
+
+import (
+  "fmt"
+  "github.com/MobRulesGames/glop/gin"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "github.com/MobRulesGames/opengl/gl"
+  "path/filepath"
+  "sort"
+  "time"
+)
+
+type chatLayout struct {
+  Dx    int
+  Lines int
+  Size  int
+
+  // Entry.Button sends whatever is in the entry.
+  Entry TextEntry
+}
+
+// Shows the chat messages in an online game, and lets the player send new
+// ones.  The messages come from the mrgnet.Game we last fetched, so new
+// messages show up whenever a turn is fetched.
+type ChatPanel struct {
+  layout chatLayout
+  region gui.Region
+  gp     *GamePanel
+
+  // Position of the mouse
+  mx, my int
+  last_t int64
+
+  // Number of messages the last time we looked, when more than this many
+  // show up the panel lights up for a while.
+  seen        int
+  alpha       float64
+  update_time time.Time
+
+  sent chan mrgnet.ChatResponse
+}
+
+func MakeChatPanel(gp *GamePanel) (*ChatPanel, error) {
+  var cp ChatPanel
+  datadir := base.GetDataDir()
+  err := base.LoadAndProcessObject(filepath.Join(datadir, "ui", "chat.json"), "json", &cp.layout)
+  if err != nil {
+    return nil, err
+  }
+  cp.gp = gp
+  cp.sent = make(chan mrgnet.ChatResponse, 1)
+  cp.layout.Entry.Button.f = func(interface{}) {
+    cp.send()
+  }
+  return &cp, nil
+}
+
+// Returns the game that the chat belongs to, or nil if this isn't an online
+// game or we haven't fetched it yet.
+func (cp *ChatPanel) netGame() *mrgnet.Game {
+  if cp.gp.game == nil || cp.gp.game.net.key == "" {
+    return nil
+  }
+  return cp.gp.game.net.game
+}
+
+// Spectators can read the chat but can't add to it.
+func (cp *ChatPanel) canSend() bool {
+  return cp.netGame() != nil && !cp.gp.game.net.spectator
+}
+
+func (cp *ChatPanel) send() {
+  text := cp.layout.Entry.Text()
+  if text == "" || !cp.canSend() {
+    return
+  }
+  cp.layout.Entry.SetText("")
+  req := mrgnet.ChatRequest{Id: getNetId(), Game_key: cp.gp.game.net.key, Text: text}
+  go func() {
+    var resp mrgnet.ChatResponse
+    if err := mrgnet.DoAction("chat", req, &resp); err != nil {
+      resp.Err = netErrorMessage(err)
+    }
+    cp.sent <- resp
+  }()
+}
+
+// Returns the messages in the order they were sent.
+func (cp *ChatPanel) messages() []mrgnet.ChatMessage {
+  game := cp.netGame()
+  if game == nil {
+    return nil
+  }
+  var rounds []int
+  for round := range game.Chat {
+    rounds = append(rounds, round)
+  }
+  sort.Ints(rounds)
+  var msgs []mrgnet.ChatMessage
+  for _, round := range rounds {
+    msgs = append(msgs, game.Chat[round]...)
+  }
+  return msgs
+}
+
+func (cp *ChatPanel) Requested() gui.Dims {
+  d := base.GetDictionary(cp.layout.Size)
+  return gui.Dims{cp.layout.Dx, int(d.MaxHeight()) * (cp.layout.Lines + 2)}
+}
+
+func (cp *ChatPanel) Expandable() (bool, bool) {
+  return false, false
+}
+
+func (cp *ChatPanel) Rendered() gui.Region {
+  return cp.region
+}
+
+func (cp *ChatPanel) Respond(g *gui.Gui, group gui.EventGroup) bool {
+  if !cp.canSend() {
+    return false
+  }
+  cursor := group.Events[0].Key.Cursor()
+  if cursor != nil {
+    cp.mx, cp.my = cursor.Point()
+  }
+  if found, event := group.FindEvent(gin.MouseLButton); found && event.Type == gin.Press {
+    if cp.layout.Entry.handleClick(cp.mx, cp.my, nil) {
+      return true
+    }
+  }
+  cp.layout.Entry.Respond(group, nil)
+
+  // Don't let anything else see keys that were typed into the chat.
+  return cp.layout.Entry.HasFocus() && cursor == nil
+}
+
+func (cp *ChatPanel) Think(g *gui.Gui, t int64) {
+  if cp.last_t == 0 {
+    cp.last_t = t
+  }
+  dt := t - cp.last_t
+  cp.last_t = t
+
+  select {
+  case resp := <-cp.sent:
+    if resp.Err != "" {
+      base.Error().Printf("Unable to send chat message: %s", resp.Err)
+    } else if game := cp.netGame(); game != nil {
+      game.Chat = resp.Chat
+    }
+  default:
+  }
+
+  if n := len(cp.messages()); n > cp.seen {
+    cp.seen = n
+    cp.alpha = 1.0
+    cp.update_time = time.Now()
+  }
+  if cp.alpha > 0.5 && !cp.layout.Entry.HasFocus() && time.Now().Sub(cp.update_time).Seconds() >= 5 {
+    cp.alpha = doApproach(cp.alpha, 0.5, dt)
+  }
+  if cp.layout.Entry.HasFocus() {
+    cp.alpha = 1.0
+    cp.update_time = time.Now()
+  }
+
+  mx, my := cp.mx, cp.my
+  if !cp.canSend() {
+    mx, my = 0, 0
+  }
+  cp.layout.Entry.Think(cp.region.X, cp.region.Y, mx, my, dt)
+}
+
+func (cp *ChatPanel) Draw(region gui.Region) {
+  cp.region = region
+  if cp.netGame() == nil {
+    return
+  }
+  d := base.GetDictionary(cp.layout.Size)
+  msgs := cp.messages()
+  if len(msgs) > cp.layout.Lines {
+    msgs = msgs[len(msgs)-cp.layout.Lines:]
+  }
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, byte(255*cp.alpha))
+  y := region.Y + region.Dy
+  for _, msg := range msgs {
+    y -= int(d.MaxHeight())
+    d.RenderString(fmt.Sprintf("%s: %s", msg.Name, msg.Text), float64(region.X), float64(y), 0, d.MaxHeight(), gui.Left)
+  }
+  if cp.canSend() {
+    cp.layout.Entry.RenderAt(region.X, region.Y)
+  }
+}
+
+func (cp *ChatPanel) DrawFocused(region gui.Region) {
+  cp.Draw(region)
+}
+
+func (cp *ChatPanel) String() string {
+  return "chat panel"
+}
@@ -85,6 +85,9 @@ type mainBarState struct {
     // handling.
     clicked Action
 
+    // Like clicked, but the action was right-clicked to ready it.
+    readied Action
+
     space float64
   }
   Conditions struct {
@@ -285,6 +288,10 @@ func (m *MainBar) Think(g *gui.Gui, t int64) {
       }
       m.state.Actions.clicked = nil
     }
+    if m.state.Actions.readied != nil {
+      m.game.ReadyAction(m.ent, m.state.Actions.readied)
+      m.state.Actions.readied = nil
+    }
 
     // We similarly need to scroll through conditions
     c := m.layout.Conditions
@@ -327,6 +334,9 @@ func (m *MainBar) Think(g *gui.Gui, t int64) {
     if index := m.pointInsideAction(m.mx, m.my); index != -1 {
       m.state.MouseOver.active = true
       m.state.MouseOver.text = m.ent.Actions[index].String()
+      if m.ent.Actions[index].Readyable() {
+        m.state.MouseOver.text += " (right-click to ready)"
+      }
       m.state.MouseOver.location = mouseOverActions
     }
   }
@@ -397,6 +407,16 @@ func (m *MainBar) Respond(g *gui.Gui, group gui.EventGroup) bool {
     }
   }
 
+  if found, event := group.FindEvent(gin.MouseRButton); found && event.Type == gin.Press {
+    if m.ent != nil {
+      index := m.pointInsideAction(m.mx, m.my)
+      if index != -1 {
+        m.state.Actions.readied = m.ent.Actions[index]
+        return true
+      }
+    }
+  }
+
   if found, event := group.FindEvent(gin.MouseWheelVertical); found {
     x := int(m.layout.Conditions.X)
     y := int(m.layout.Conditions.Y)
@@ -513,10 +533,15 @@ func (m *MainBar) Draw(region gui.Region) {
       gl.Color4d(1, 1, 1, 1)
       for i, action := range m.ent.Actions {
 
-        // Highlight the selected action
-        if action == m.game.current_action {
+        // Highlight the selected action, or the readied one
+        readied := m.ent.Readied != nil && m.ent.Readied.Index == i
+        if action == m.game.current_action || readied {
           gl.Disable(gl.TEXTURE_2D)
-          gl.Color4d(1, 0, 0, 1)
+          if action == m.game.current_action {
+            gl.Color4d(1, 0, 0, 1)
+          } else {
+            gl.Color4d(1, 1, 0, 1)
+          }
           gl.Begin(gl.QUADS)
           gl.Vertex3d(xpos-2, m.layout.Actions.Y-2, 0)
           gl.Vertex3d(xpos-2, m.layout.Actions.Y+s+2, 0)
@@ -563,6 +588,20 @@ func (m *MainBar) Draw(region gui.Region) {
         str := fmt.Sprintf("%s:%dAP", m.state.Actions.selected.String(), m.state.Actions.selected.AP())
         gl.Color4d(1, 1, 1, 1)
         d.RenderString(str, x, y, 0, d.MaxHeight(), gui.Center)
+
+        // While an attack is being targeted show its odds against whatever
+        // ent is under the cursor.
+        if attack, ok := m.state.Actions.selected.(AttackPreviewer); ok && m.game.HoveredEnt() != nil {
+          if odds, ok := attack.PreviewAttack(m.game.HoveredEnt()); ok {
+            str := fmt.Sprintf("%d%% to hit, %.1f damage", int(odds.Hit*100+0.5), odds.Damage)
+            if odds.Cover > 0 {
+              str += fmt.Sprintf(", +%d cover", odds.Cover)
+            }
+            y += d.MaxHeight()
+            d := base.GetDictionary(10)
+            d.RenderString(str, x, y, 0, d.MaxHeight(), gui.Center)
+          }
+        }
       }
     }
 
@@ -0,0 +1,148 @@
+package game
+
+import (
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// Asks the player for their side, map and goal with the same choosers that
+// versus games use, then puts the online menu back and queues the player for
+// a match.  Backing out of any of the choosers goes back to the online menu.
+func insertMatchChoosers(ui gui.WidgetParent) error {
+  side_chooser, side_done, err := makeChooseSideMenu()
+  if err != nil {
+    return err
+  }
+  ui.AddChild(side_chooser)
+  go func() {
+    m := <-side_done
+    ui.RemoveChild(side_chooser)
+    if m == nil || len(m) != 1 {
+      returnToOnlineMenu(ui, nil)
+      return
+    }
+    var req mrgnet.MatchRequest
+    // Pass-and-play doesn't mean anything here, so we take it to mean that
+    // the player doesn't care which side they play.
+    if m[0] != "Humans" {
+      req.Side = m[0]
+    }
+    err := InsertMapChooser(
+      ui,
+      func(name string) {
+        req.Map = name
+        goal_chooser, goal_done, err := makeChooseGoalMenu()
+        if err != nil {
+          base.Error().Printf("Error making goal menu: %v", err)
+          returnToOnlineMenu(ui, nil)
+          return
+        }
+        ui.AddChild(goal_chooser)
+        go func() {
+          m := <-goal_done
+          ui.RemoveChild(goal_chooser)
+          if m == nil || len(m) != 1 {
+            returnToOnlineMenu(ui, nil)
+            return
+          }
+          req.Goal = m[0]
+          returnToOnlineMenu(ui, &req)
+        }()
+      },
+      InsertOnlineMenu,
+    )
+    if err != nil {
+      base.Error().Printf("Error making map chooser: %v", err)
+      returnToOnlineMenu(ui, nil)
+    }
+  }()
+  return nil
+}
+
+// Puts the online menu back, and if req is not nil starts looking for a
+// match with it.
+func returnToOnlineMenu(ui gui.WidgetParent, req *mrgnet.MatchRequest) {
+  sm, err := insertOnlineMenu(ui)
+  if err != nil {
+    base.Error().Printf("Unable to make online menu: %v", err)
+    return
+  }
+  if req != nil {
+    sm.findMatch(*req)
+  }
+}
+
+// Queues the player for a match.  If the player gets the denizens the game
+// starts right away, since they are the one that sets it up, otherwise the
+// game shows up in their list of games once the denizens have moved.
+func (sm *OnlineMenu) findMatch(req mrgnet.MatchRequest) {
+  req.Id = net_id
+  req.Timeout = mrgnet.Long_poll_duration
+  <-sm.control.in
+  sm.matching = true
+  sm.layout.FindMatch.Text.String = "Cancel Matchmaking"
+  sm.notify("Looking for a match...")
+  sm.control.out <- struct{}{}
+
+  go func() {
+    for {
+      var resp mrgnet.MatchResponse
+      err := mrgnet.DoLongPoll("match", req, &resp, req.Timeout)
+      if err != nil {
+        resp.Err = netErrorMessage(err)
+      }
+      <-sm.control.in
+      if !sm.matching {
+        // Cancelled while we were waiting, if we were matched anyway the game
+        // will be in the list.
+        if resp.Matched {
+          sm.refresh()
+        }
+        sm.control.out <- struct{}{}
+        return
+      }
+      if resp.Err != "" {
+        sm.layout.Error.err = resp.Err
+        base.Error().Printf("Matchmaking failed: %s", resp.Err)
+        sm.stopMatching()
+        sm.control.out <- struct{}{}
+        return
+      }
+      if !resp.Matched {
+        sm.control.out <- struct{}{}
+        continue
+      }
+      sm.stopMatching()
+      base.Log().Printf("Matched as %s on %s in game %s", resp.Side, resp.Map, resp.Game_key)
+      if resp.Side == "Denizens" {
+        sm.ui.RemoveChild(sm)
+        sm.ui.AddChild(MakeGamePanel(resp.Map, nil, map[string]string{"goal": resp.Goal}, resp.Game_key))
+      } else {
+        sm.notify("Matched!  Waiting on the Denizens.")
+        sm.refresh()
+      }
+      sm.control.out <- struct{}{}
+      return
+    }
+  }()
+}
+
+// Must be called from Think or while holding control.
+func (sm *OnlineMenu) stopMatching() {
+  sm.matching = false
+  sm.layout.FindMatch.Text.String = "Find Match"
+}
+
+// Takes the player out of the matchmaking queue.  Must be called from Think
+// or while holding control.
+func (sm *OnlineMenu) cancelMatch() {
+  sm.stopMatching()
+  req := mrgnet.MatchRequest{Id: net_id, Cancel: true}
+  go func() {
+    var resp mrgnet.MatchResponse
+    if err := mrgnet.DoAction("match", req, &resp); err != nil {
+      base.Warn().Printf("Unable to leave the matchmaking queue: %v", err)
+    }
+  }()
+}
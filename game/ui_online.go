@@ -29,9 +29,24 @@ type gameListBox struct {
 
 type gameField struct {
   join, delete ButtonLike
-  name         string
-  key          mrgnet.GameKey
-  game         mrgnet.Game
+
+  // Saves a replay of the game, only for games that have started.
+  export ButtonLike
+
+  name string
+  key  mrgnet.GameKey
+  game mrgnet.Game
+}
+
+// Returns the buttons that go with this game, delete and export may be nil.
+func (gf *gameField) buttons() []ButtonLike {
+  buttons := []ButtonLike{gf.join}
+  for _, b := range []ButtonLike{gf.delete, gf.export} {
+    if b != nil {
+      buttons = append(buttons, b)
+    }
+  }
+  return buttons
 }
 
 type onlineLayout struct {
@@ -43,8 +58,22 @@ type onlineLayout struct {
   Back       Button
 
   User    TextEntry
+  Server  TextEntry
   NewGame Button
 
+  // Save the player's identity to, or load it from, identity_backup_file.
+  BackupId, RestoreId Button
+
+  // Toggles which side's visibility we follow when spectating.
+  Follow Button
+
+  // Cycles through turn_deadlines for new games.
+  Deadline Button
+
+  // Asks for matchmaking preferences and then queues the player, or takes
+  // them out of the queue if they are already in it.
+  FindMatch Button
+
   GameStats struct {
     X, Y, Dx, Dy int
     Size         int
@@ -62,7 +91,7 @@ type onlineLayout struct {
     Justification string
   }
 
-  Unstarted, Active gameListBox
+  Unstarted, Active, Spectate gameListBox
 }
 
 type OnlineMenu struct {
@@ -73,6 +102,7 @@ type OnlineMenu struct {
   last_t  int64
 
   update_user  chan mrgnet.UpdateUserResponse
+  update_text  string
   update_alpha float64
   update_time  time.Time
 
@@ -84,16 +114,47 @@ type OnlineMenu struct {
   ui gui.WidgetParent
 
   hover_game *gameField
+
+  // Side whose visibility we see in games we spectate.
+  follow Side
+
+  // Index into turn_deadlines of the deadline for new games.
+  deadline int
+
+  // True while we're in the matchmaking queue.
+  matching bool
+}
+
+// Turn deadlines that can be chosen for new games, zero means no deadline.
+var turn_deadlines = []time.Duration{0, 24 * time.Hour, 72 * time.Hour, 168 * time.Hour}
+
+func formatDeadline(d time.Duration) string {
+  switch {
+  case d <= 0:
+    return "None"
+  case d == 24*time.Hour:
+    return "1 day"
+  case d%(24*time.Hour) == 0:
+    return fmt.Sprintf("%d days", d/(24*time.Hour))
+  case d >= time.Hour:
+    return fmt.Sprintf("%d hours", d/time.Hour)
+  }
+  return fmt.Sprintf("%d minutes", d/time.Minute)
 }
 
 var net_id mrgnet.NetId
 
 func InsertOnlineMenu(ui gui.WidgetParent) error {
+  _, err := insertOnlineMenu(ui)
+  return err
+}
+
+func insertOnlineMenu(ui gui.WidgetParent) (*OnlineMenu, error) {
   var sm OnlineMenu
   datadir := base.GetDataDir()
   err := base.LoadAndProcessObject(filepath.Join(datadir, "ui", "start", "online", "layout.json"), "json", &sm.layout)
   if err != nil {
-    return err
+    return nil, err
   }
   sm.buttons = []ButtonLike{
     &sm.layout.Back,
@@ -101,22 +162,36 @@ func InsertOnlineMenu(ui gui.WidgetParent) error {
     &sm.layout.Unstarted.Down,
     &sm.layout.Active.Up,
     &sm.layout.Active.Down,
+    &sm.layout.Spectate.Up,
+    &sm.layout.Spectate.Down,
     &sm.layout.User,
+    &sm.layout.Server,
     &sm.layout.NewGame,
+    &sm.layout.BackupId,
+    &sm.layout.RestoreId,
+    &sm.layout.Follow,
+    &sm.layout.Deadline,
+    &sm.layout.FindMatch,
   }
   sm.control.in = make(chan struct{})
   sm.control.out = make(chan struct{})
   sm.layout.Back.f = func(interface{}) {
+    if sm.matching {
+      sm.cancelMatch()
+    }
     ui.RemoveChild(&sm)
     InsertStartMenu(ui)
   }
   sm.ui = ui
 
-  fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
-  if net_id == 0 {
-    net_id = mrgnet.NetId(mrgnet.RandomId())
-    base.SetStoreVal("netid", fmt.Sprintf("%d", net_id))
+  err = ConfigureNet()
+  if err != nil {
+    sm.layout.Error.err = err.Error()
+    base.Error().Printf("Unable to configure server: %v", err)
   }
+  sm.layout.Server.Entry.Default = mrgnet.GetConfig().Host_url
+
+  net_id = getNetId()
 
   in_newgame := false
   sm.layout.NewGame.f = func(interface{}) {
@@ -127,16 +202,10 @@ func InsertOnlineMenu(ui gui.WidgetParent) error {
     go func() {
       var req mrgnet.NewGameRequest
       req.Id = net_id
+      req.Turn_deadline = turn_deadlines[sm.deadline]
       var resp mrgnet.NewGameResponse
-      done := make(chan bool, 1)
-      go func() {
-        mrgnet.DoAction("new", req, &resp)
-        done <- true
-      }()
-      select {
-      case <-done:
-      case <-time.After(5 * time.Second):
-        resp.Err = "Couldn't connect to server."
+      if err := mrgnet.DoAction("new", req, &resp); err != nil {
+        resp.Err = netErrorMessage(err)
       }
       <-sm.control.in
       defer func() {
@@ -162,7 +231,7 @@ func InsertOnlineMenu(ui gui.WidgetParent) error {
     }()
   }
 
-  for _, _glb := range []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted} {
+  for _, _glb := range sm.gameLists() {
     glb := _glb
     glb.Up.f = func(interface{}) {
       glb.Scroll.Up()
@@ -179,16 +248,73 @@ func InsertOnlineMenu(ui gui.WidgetParent) error {
 
     glb.update = make(chan mrgnet.ListGamesResponse)
   }
-  go func() {
-    var resp mrgnet.ListGamesResponse
-    mrgnet.DoAction("list", mrgnet.ListGamesRequest{Id: net_id, Unstarted: true}, &resp)
-    sm.layout.Unstarted.update <- resp
-  }()
-  go func() {
-    var resp mrgnet.ListGamesResponse
-    mrgnet.DoAction("list", mrgnet.ListGamesRequest{Id: net_id, Unstarted: false}, &resp)
-    sm.layout.Active.update <- resp
-  }()
+  sm.refresh()
+
+  sm.layout.Server.Button.f = func(interface{}) {
+    err := SetServerUrl(sm.layout.Server.Entry.text)
+    if err != nil {
+      sm.layout.Error.err = err.Error()
+      base.Error().Printf("Unable to change server: %v", err)
+      return
+    }
+    sm.layout.Error.err = ""
+    sm.layout.Server.SetText(mrgnet.GetConfig().Host_url)
+    sm.refresh()
+  }
+
+  sm.follow = SideHaunt
+  sm.layout.Follow.Text.String = "Follow: Denizens"
+  sm.layout.Follow.f = func(interface{}) {
+    if sm.follow == SideHaunt {
+      sm.follow = SideExplorers
+      sm.layout.Follow.Text.String = "Follow: Intruders"
+    } else {
+      sm.follow = SideHaunt
+      sm.layout.Follow.Text.String = "Follow: Denizens"
+    }
+  }
+
+  sm.layout.Deadline.Text.String = "Turn Deadline: " + formatDeadline(turn_deadlines[sm.deadline])
+  sm.layout.Deadline.f = func(interface{}) {
+    sm.deadline = (sm.deadline + 1) % len(turn_deadlines)
+    sm.layout.Deadline.Text.String = "Turn Deadline: " + formatDeadline(turn_deadlines[sm.deadline])
+  }
+
+  sm.layout.FindMatch.f = func(interface{}) {
+    if sm.matching {
+      sm.cancelMatch()
+      return
+    }
+    ui.RemoveChild(&sm)
+    err := insertMatchChoosers(ui)
+    if err != nil {
+      base.Error().Printf("Unable to make matchmaking menu: %v", err)
+    }
+  }
+
+  sm.layout.BackupId.f = func(interface{}) {
+    path := filepath.Join(base.GetDataDir(), identity_backup_file)
+    err := ExportIdentity(path)
+    if err != nil {
+      sm.layout.Error.err = err.Error()
+      base.Error().Printf("Unable to back up identity: %v", err)
+      return
+    }
+    sm.notify(fmt.Sprintf("Identity saved to %s", identity_backup_file))
+  }
+  sm.layout.RestoreId.f = func(interface{}) {
+    path := filepath.Join(base.GetDataDir(), identity_backup_file)
+    err := ImportIdentity(path)
+    if err != nil {
+      sm.layout.Error.err = err.Error()
+      base.Error().Printf("Unable to restore identity: %v", err)
+      return
+    }
+    net_id = getNetId()
+    sm.layout.Error.err = ""
+    sm.refresh()
+    sm.notify(fmt.Sprintf("Identity restored from %s", identity_backup_file))
+  }
 
   sm.layout.User.Button.f = func(interface{}) {
     var req mrgnet.UpdateUserRequest
@@ -196,26 +322,69 @@ func InsertOnlineMenu(ui gui.WidgetParent) error {
     req.Id = net_id
     var resp mrgnet.UpdateUserResponse
     go func() {
-      mrgnet.DoAction("user", req, &resp)
+      if err := mrgnet.DoAction("user", req, &resp); err != nil {
+        sm.reportError(netErrorMessage(err))
+        return
+      }
       <-sm.control.in
       sm.layout.User.SetText(resp.Name)
-      sm.update_alpha = 1.0
-      sm.update_time = time.Now()
+      sm.notify("Name Updated")
       sm.control.out <- struct{}{}
     }()
   }
+
+  ui.AddChild(&sm)
+  return &sm, nil
+}
+
+func (sm *OnlineMenu) gameLists() []*gameListBox {
+  return []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted, &sm.layout.Spectate}
+}
+
+// Fetches the user's name and all of the lists of games from the server.
+func (sm *OnlineMenu) refresh() {
+  for _, _glb := range sm.gameLists() {
+    glb := _glb
+    go func() {
+      var resp mrgnet.ListGamesResponse
+      req := mrgnet.ListGamesRequest{
+        Id:        net_id,
+        Unstarted: glb == &sm.layout.Unstarted,
+        Spectate:  glb == &sm.layout.Spectate,
+      }
+      if err := mrgnet.DoAction("list", req, &resp); err != nil {
+        sm.reportError(netErrorMessage(err))
+      }
+      glb.update <- resp
+    }()
+  }
   go func() {
     var resp mrgnet.UpdateUserResponse
-    mrgnet.DoAction("user", mrgnet.UpdateUserRequest{Id: net_id}, &resp)
+    if err := mrgnet.DoAction("user", mrgnet.UpdateUserRequest{Id: net_id}, &resp); err != nil {
+      sm.reportError(netErrorMessage(err))
+      return
+    }
     <-sm.control.in
     sm.layout.User.SetText(resp.Name)
-    sm.update_alpha = 1.0
-    sm.update_time = time.Now()
+    sm.notify("Name Updated")
     sm.control.out <- struct{}{}
   }()
+}
 
-  ui.AddChild(&sm)
-  return nil
+// Briefly shows a message next to the user's name.  Must be called from Think
+// or while holding control.
+func (sm *OnlineMenu) notify(text string) {
+  sm.update_text = text
+  sm.update_alpha = 1.0
+  sm.update_time = time.Now()
+}
+
+// Shows an error to the player, can be called from any go-routine.
+func (sm *OnlineMenu) reportError(msg string) {
+  base.Error().Printf("Online: %s", msg)
+  <-sm.control.in
+  sm.layout.Error.err = msg
+  sm.control.out <- struct{}{}
 }
 
 func (sm *OnlineMenu) Requested() gui.Dims {
@@ -259,10 +428,10 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
     }
   }
 
-  var net_id mrgnet.NetId
-  fmt.Sscanf(base.GetStoreVal("netid"), "%d", &net_id)
-  for i := range []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted} {
-    glb := []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted}[i]
+  net_id := getNetId()
+  sm.hover_game = nil
+  for i := range sm.gameLists() {
+    glb := sm.gameLists()[i]
     select {
     case list := <-glb.update:
       glb.games = glb.games[0:0]
@@ -280,27 +449,35 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
         b.Text.String = "Join!"
         game_key := list.Game_keys[j]
         active := (glb == &sm.layout.Active)
+        spectate := (glb == &sm.layout.Spectate)
+        if spectate {
+          b.Text.String = "Watch!"
+        }
         in_joingame := false
         b.f = func(interface{}) {
           if in_joingame {
             return
           }
           in_joingame = true
-          if active {
+          if spectate {
+            follow := sm.follow
+            go func() {
+              <-sm.control.in
+              defer func() {
+                in_joingame = false
+                sm.control.out <- struct{}{}
+              }()
+              sm.ui.RemoveChild(sm)
+              sm.ui.AddChild(MakeSpectatorPanel(game_key, follow))
+            }()
+          } else if active {
             go func() {
               var req mrgnet.StatusRequest
               req.Id = net_id
               req.Game_key = game_key
               var resp mrgnet.StatusResponse
-              done := make(chan bool, 1)
-              go func() {
-                mrgnet.DoAction("status", req, &resp)
-                done <- true
-              }()
-              select {
-              case <-done:
-              case <-time.After(5 * time.Second):
-                resp.Err = "Couldn't connect to server."
+              if err := mrgnet.DoAction("status", req, &resp); err != nil {
+                resp.Err = netErrorMessage(err)
               }
               <-sm.control.in
               defer func() {
@@ -321,15 +498,8 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
               req.Id = net_id
               req.Game_key = game_key
               var resp mrgnet.JoinGameResponse
-              done := make(chan bool, 1)
-              go func() {
-                mrgnet.DoAction("join", req, &resp)
-                done <- true
-              }()
-              select {
-              case <-done:
-              case <-time.After(5 * time.Second):
-                resp.Err = "Couldn't connect to server."
+              if err := mrgnet.DoAction("join", req, &resp); err != nil {
+                resp.Err = netErrorMessage(err)
               }
               <-sm.control.in
               defer func() {
@@ -346,6 +516,26 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
             }()
           }
         }
+        var export ButtonLike
+        if active || spectate {
+          e := Button{}
+          e.Text.String = "Save!"
+          e.Text.Justification = "right"
+          e.Text.Size = sm.layout.Text.Size
+          e.f = func(interface{}) {
+            go func() {
+              path, err := ExportReplay(game_key)
+              if err != nil {
+                sm.reportError(fmt.Sprintf("Unable to save replay: %v", err))
+                return
+              }
+              <-sm.control.in
+              sm.notify(fmt.Sprintf("Replay saved to %s", filepath.Base(path)))
+              sm.control.out <- struct{}{}
+            }()
+          }
+          export = &e
+        }
         if active {
           d := Button{}
           d.Text.String = "Delete!"
@@ -357,15 +547,8 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
               req.Id = net_id
               req.Game_key = game_key
               var resp mrgnet.KillResponse
-              done := make(chan bool, 1)
-              go func() {
-                mrgnet.DoAction("kill", req, &resp)
-                done <- true
-              }()
-              select {
-              case <-done:
-              case <-time.After(5 * time.Second):
-                resp.Err = "Couldn't connect to server."
+              if err := mrgnet.DoAction("kill", req, &resp); err != nil {
+                resp.Err = netErrorMessage(err)
               }
               <-sm.control.in
               if resp.Err != "" {
@@ -379,9 +562,9 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
               sm.control.out <- struct{}{}
             }()
           }
-          glb.games = append(glb.games, gameField{&b, &d, name, list.Game_keys[j], list.Games[j]})
+          glb.games = append(glb.games, gameField{&b, &d, export, name, list.Game_keys[j], list.Games[j]})
         } else {
-          glb.games = append(glb.games, gameField{&b, nil, name, list.Game_keys[j], list.Games[j]})
+          glb.games = append(glb.games, gameField{&b, nil, export, name, list.Game_keys[j], list.Games[j]})
         }
       }
       glb.Scroll.Height = int(base.GetDictionary(sm.layout.Text.Size).MaxHeight() * float64(len(list.Games)))
@@ -389,7 +572,6 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
     default:
     }
 
-    sm.hover_game = nil
     if (gui.Point{sm.mx, sm.my}.Inside(glb.Scroll.Region())) {
       for i := range glb.games {
         game := &glb.games[i]
@@ -401,16 +583,14 @@ func (sm *OnlineMenu) Think(g *gui.Gui, t int64) {
         if (gui.Point{sm.mx, sm.my}.Inside(region)) {
           sm.hover_game = game
         }
-        game.join.Think(sm.region.X, sm.region.Y, sm.mx, sm.my, dt)
-        if game.delete != nil {
-          game.delete.Think(sm.region.X, sm.region.Y, sm.mx, sm.my, dt)
+        for _, button := range game.buttons() {
+          button.Think(sm.region.X, sm.region.Y, sm.mx, sm.my, dt)
         }
       }
     } else {
-      for _, game := range glb.games {
-        game.join.Think(sm.region.X, sm.region.Y, 0, 0, dt)
-        if game.delete != nil {
-          game.delete.Think(sm.region.X, sm.region.Y, 0, 0, dt)
+      for i := range glb.games {
+        for _, button := range glb.games[i].buttons() {
+          button.Think(sm.region.X, sm.region.Y, 0, 0, dt)
         }
       }
     }
@@ -437,15 +617,14 @@ func (sm *OnlineMenu) Respond(g *gui.Gui, group gui.EventGroup) bool {
         return true
       }
     }
-    for _, glb := range []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted} {
+    for _, glb := range sm.gameLists() {
       inside := gui.Point{sm.mx, sm.my}.Inside(glb.Scroll.Region())
       if cursor == nil || inside {
-        for _, game := range glb.games {
-          if game.join.handleClick(sm.mx, sm.my, nil) {
-            return true
-          }
-          if game.delete != nil && game.delete.handleClick(sm.mx, sm.my, nil) {
-            return true
+        for i := range glb.games {
+          for _, button := range glb.games[i].buttons() {
+            if button.handleClick(sm.mx, sm.my, nil) {
+              return true
+            }
           }
         }
       }
@@ -458,15 +637,14 @@ func (sm *OnlineMenu) Respond(g *gui.Gui, group gui.EventGroup) bool {
       hit = true
     }
   }
-  for _, glb := range []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted} {
+  for _, glb := range sm.gameLists() {
     inside := gui.Point{sm.mx, sm.my}.Inside(glb.Scroll.Region())
     if cursor == nil || inside {
-      for _, game := range glb.games {
-        if game.join.Respond(group, nil) {
-          hit = true
-        }
-        if game.delete != nil && game.delete.Respond(group, nil) {
-          hit = true
+      for i := range glb.games {
+        for _, button := range glb.games[i].buttons() {
+          if button.Respond(group, nil) {
+            hit = true
+          }
         }
       }
     }
@@ -488,7 +666,7 @@ func (sm *OnlineMenu) Draw(region gui.Region) {
   }
 
   d := base.GetDictionary(sm.layout.Text.Size)
-  for _, glb := range []*gameListBox{&sm.layout.Active, &sm.layout.Unstarted} {
+  for _, glb := range sm.gameLists() {
     title_d := base.GetDictionary(glb.Title.Size)
     title_x := float64(glb.Scroll.X + glb.Scroll.Dx/2)
     title_y := float64(glb.Scroll.Y + glb.Scroll.Dy)
@@ -508,6 +686,9 @@ func (sm *OnlineMenu) Draw(region gui.Region) {
       if game.delete != nil {
         game.delete.RenderAt(sx+50+glb.Scroll.Dx-100, sy)
       }
+      if game.export != nil {
+        game.export.RenderAt(sx+50+glb.Scroll.Dx-160, sy)
+      }
     }
     glb.Scroll.Region().PopClipPlanes()
   }
@@ -515,7 +696,7 @@ func (sm *OnlineMenu) Draw(region gui.Region) {
   gl.Color4ub(255, 255, 255, byte(255*sm.update_alpha))
   sx := sm.layout.User.Entry.X + sm.layout.User.Entry.Dx + 10
   sy := sm.layout.User.Button.Y
-  d.RenderString("Name Updated", float64(sx), float64(sy), 0, d.MaxHeight(), gui.Left)
+  d.RenderString(sm.update_text, float64(sx), float64(sy), 0, d.MaxHeight(), gui.Left)
 
   if sm.hover_game != nil {
     game := sm.hover_game
@@ -525,28 +706,51 @@ func (sm *OnlineMenu) Draw(region gui.Region) {
     x := float64(sm.layout.GameStats.X + sm.layout.GameStats.Dx/2)
     y := float64(sm.layout.GameStats.Y+sm.layout.GameStats.Dy) - d.MaxHeight()
 
-    if game.game.Denizens_id == net_id {
-      d.RenderString("You: Denizens", x, y, 0, d.MaxHeight(), gui.Center)
+    if game.game.Intruders_id != 0 && game.game.Denizens_id != net_id && game.game.Intruders_id != net_id {
+      // A game we can only spectate.
+      d.RenderString(fmt.Sprintf("Denizens: %s", game.game.Denizens_name), x, y, 0, d.MaxHeight(), gui.Center)
+      y -= d.MaxHeight()
+      d.RenderString(fmt.Sprintf("Intruders: %s", game.game.Intruders_name), x, y, 0, d.MaxHeight(), gui.Center)
+      y -= d.MaxHeight()
+      d.RenderString(fmt.Sprintf("Round %d", len(game.game.Execs)/2+1), x, y, 0, d.MaxHeight(), gui.Center)
     } else {
-      d.RenderString("You: Intruders", x, y, 0, d.MaxHeight(), gui.Center)
-    }
-    y -= d.MaxHeight()
-    if game.game.Denizens_id == net_id {
-      var opponent string
-      if game.game.Intruders_name == "" {
-        opponent = "no opponent yet"
+      if game.game.Denizens_id == net_id {
+        d.RenderString("You: Denizens", x, y, 0, d.MaxHeight(), gui.Center)
       } else {
-        opponent = fmt.Sprintf("Vs: %s", game.game.Intruders_name)
+        d.RenderString("You: Intruders", x, y, 0, d.MaxHeight(), gui.Center)
+      }
+      y -= d.MaxHeight()
+      if game.game.Denizens_id == net_id {
+        var opponent string
+        if game.game.Intruders_name == "" {
+          opponent = "no opponent yet"
+        } else {
+          opponent = fmt.Sprintf("Vs: %s", game.game.Intruders_name)
+        }
+        d.RenderString(opponent, x, y, 0, d.MaxHeight(), gui.Center)
+      } else {
+        d.RenderString(fmt.Sprintf("Vs: %s", game.game.Denizens_name), x, y, 0, d.MaxHeight(), gui.Center)
+      }
+      y -= d.MaxHeight()
+      if (game.game.Denizens_id == net_id) == (len(game.game.Execs)%2 == 0) {
+        d.RenderString("Your move", x, y, 0, d.MaxHeight(), gui.Center)
+      } else {
+        d.RenderString("Their move", x, y, 0, d.MaxHeight(), gui.Center)
       }
-      d.RenderString(opponent, x, y, 0, d.MaxHeight(), gui.Center)
-    } else {
-      d.RenderString(fmt.Sprintf("Vs: %s", game.game.Denizens_name), x, y, 0, d.MaxHeight(), gui.Center)
     }
     y -= d.MaxHeight()
-    if (game.game.Denizens_id == net_id) == (len(game.game.Execs)%2 == 0) {
-      d.RenderString("Your move", x, y, 0, d.MaxHeight(), gui.Center)
+    if game.game.Turn_deadline > 0 {
+      d.RenderString(fmt.Sprintf("Deadline: %s", formatDeadline(game.game.Turn_deadline)), x, y, 0, d.MaxHeight(), gui.Center)
+      if game.game.Intruders_id != 0 {
+        y -= d.MaxHeight()
+        left := game.game.Turn_deadline - time.Now().Sub(game.game.Turn_started)
+        if left < 0 {
+          left = 0
+        }
+        d.RenderString(fmt.Sprintf("Time left: %s", formatDeadline(left)), x, y, 0, d.MaxHeight(), gui.Center)
+      }
     } else {
-      d.RenderString("Their move", x, y, 0, d.MaxHeight(), gui.Center)
+      d.RenderString("No deadline", x, y, 0, d.MaxHeight(), gui.Center)
     }
   }
 
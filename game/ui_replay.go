@@ -0,0 +1,305 @@
+package game
+
+import (
+  "fmt"
+  "github.com/MobRulesGames/glop/gin"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "github.com/MobRulesGames/haunts/sound"
+  "github.com/MobRulesGames/opengl/gl"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+)
+
+type replayLayout struct {
+  Dx, Dy int
+
+  // Size of the text that says where we are in the replay.
+  Size int
+
+  Rewind, Play, Step, Los, Quit Button
+
+  // Round.Button jumps to whatever round is in the entry.
+  Round TextEntry
+}
+
+// The controls shown at the bottom of the screen while watching a replay.
+type ReplayControls struct {
+  layout  replayLayout
+  region  gui.Region
+  buttons []ButtonLike
+  gp      *GamePanel
+
+  mx, my int
+  last_t int64
+}
+
+func MakeReplayControls(gp *GamePanel) (*ReplayControls, error) {
+  var rc ReplayControls
+  datadir := base.GetDataDir()
+  err := base.LoadAndProcessObject(filepath.Join(datadir, "ui", "replay.json"), "json", &rc.layout)
+  if err != nil {
+    return nil, err
+  }
+  rc.gp = gp
+  rc.buttons = []ButtonLike{
+    &rc.layout.Rewind,
+    &rc.layout.Play,
+    &rc.layout.Step,
+    &rc.layout.Los,
+    &rc.layout.Quit,
+    &rc.layout.Round,
+  }
+  rc.layout.Rewind.f = func(interface{}) {
+    rc.send(replayCommand{kind: replayRewind})
+  }
+  rc.layout.Play.f = func(interface{}) {
+    if rc.player().playing {
+      rc.send(replayCommand{kind: replayPause})
+    } else {
+      rc.send(replayCommand{kind: replayPlay})
+    }
+  }
+  rc.layout.Step.f = func(interface{}) {
+    rc.send(replayCommand{kind: replayStep})
+  }
+  rc.layout.Los.f = func(interface{}) {
+    rc.send(replayCommand{kind: replayToggleLos})
+  }
+  rc.layout.Round.Button.f = func(interface{}) {
+    round, err := strconv.Atoi(strings.TrimSpace(rc.layout.Round.Text()))
+    if err != nil {
+      base.Warn().Printf("'%s' isn't a round.", rc.layout.Round.Text())
+      return
+    }
+    rc.layout.Round.SetText("")
+    rc.send(replayCommand{kind: replayJump, round: round})
+  }
+  rc.layout.Quit.f = func(interface{}) {
+    gp.game.Ents = nil
+    gp.game.Think(1) // This should clean things up
+    Restart()
+  }
+  return &rc, nil
+}
+
+func (rc *ReplayControls) player() *replayPlayer {
+  return rc.gp.script.replay
+}
+
+// Commands are dropped if the replay is still busy with the last one, so
+// that the ui never waits on an exec to finish.
+func (rc *ReplayControls) send(cmd replayCommand) {
+  select {
+  case rc.player().commands <- cmd:
+  default:
+    base.Log().Printf("Replay busy, dropped command %d", cmd.kind)
+  }
+}
+
+func (rc *ReplayControls) Requested() gui.Dims {
+  return gui.Dims{rc.layout.Dx, rc.layout.Dy}
+}
+
+func (rc *ReplayControls) Expandable() (bool, bool) {
+  return false, false
+}
+
+func (rc *ReplayControls) Rendered() gui.Region {
+  return rc.region
+}
+
+func (rc *ReplayControls) Respond(g *gui.Gui, group gui.EventGroup) bool {
+  cursor := group.Events[0].Key.Cursor()
+  if cursor != nil {
+    rc.mx, rc.my = cursor.Point()
+  }
+  if found, event := group.FindEvent(gin.MouseLButton); found && event.Type == gin.Press {
+    for _, button := range rc.buttons {
+      if button.handleClick(rc.mx, rc.my, nil) {
+        return true
+      }
+    }
+  }
+  hit := false
+  for _, button := range rc.buttons {
+    if button.Respond(group, nil) {
+      hit = true
+    }
+  }
+  return hit || (rc.layout.Round.HasFocus() && cursor == nil)
+}
+
+func (rc *ReplayControls) Think(g *gui.Gui, t int64) {
+  if rc.last_t == 0 {
+    rc.last_t = t
+  }
+  dt := t - rc.last_t
+  rc.last_t = t
+  rp := rc.player()
+  if rp.playing {
+    rc.layout.Play.Text.String = "Pause"
+  } else {
+    rc.layout.Play.Text.String = "Play"
+  }
+  if rp.follow == SideHaunt {
+    rc.layout.Los.Text.String = "Los: Denizens"
+  } else {
+    rc.layout.Los.Text.String = "Los: Intruders"
+  }
+  for _, button := range rc.buttons {
+    button.Think(rc.region.X, rc.region.Y, rc.mx, rc.my, dt)
+  }
+}
+
+func (rc *ReplayControls) Draw(region gui.Region) {
+  rc.region = region
+  for _, button := range rc.buttons {
+    button.RenderAt(region.X, region.Y)
+  }
+  rp := rc.player()
+  side := "Denizens"
+  if rp.turn%2 == 1 {
+    side = "Intruders"
+  }
+  text := fmt.Sprintf("Round %d of %d, %s: %d/%d", rp.turn/2+1, (len(rp.replay.Before)+1)/2, side, rp.exec, rp.num_execs)
+  d := base.GetDictionary(rc.layout.Size)
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, 255)
+  d.RenderString(text, float64(region.X+region.Dx/2), float64(region.Y+region.Dy)-d.MaxHeight(), 0, d.MaxHeight(), gui.Center)
+}
+
+func (rc *ReplayControls) DrawFocused(region gui.Region) {
+  rc.Draw(region)
+}
+
+func (rc *ReplayControls) String() string {
+  return "replay controls"
+}
+
+// An Option for the replay chooser, showing the header of a replay file.
+type replayOption struct {
+  path   string
+  header *mrgnet.ReplayHeader
+  size   int
+
+  alpha        byte
+  was_over     bool
+  was_selected bool
+}
+
+func (ro *replayOption) String() string {
+  return ro.path
+}
+func (ro *replayOption) Draw(x, y, dx int) {
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, gl.Ubyte(ro.alpha))
+  d := base.GetDictionary(ro.size)
+  d.RenderString(ro.header.Name, float64(x), float64(y), 0, d.MaxHeight(), gui.Left)
+}
+func (ro *replayOption) DrawInfo(x, y, dx, dy int) {
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, 255)
+  h := ro.header
+  lines := []string{
+    h.Name,
+    fmt.Sprintf("Denizens: %s", h.Denizens_name),
+    fmt.Sprintf("Intruders: %s", h.Intruders_name),
+    fmt.Sprintf("Played in %s", h.House),
+    fmt.Sprintf("%d rounds", (h.Turns+1)/2),
+  }
+  if h.Winner != "" {
+    lines = append(lines, fmt.Sprintf("The %s won", h.Winner))
+  }
+  d := base.GetDictionary(ro.size)
+  ty := float64(y + dy)
+  for _, line := range lines {
+    ty -= d.MaxHeight()
+    d.RenderString(line, float64(x), ty, 0, d.MaxHeight(), gui.Left)
+  }
+}
+func (ro *replayOption) Height() int {
+  return int(base.GetDictionary(ro.size).MaxHeight())
+}
+func (ro *replayOption) Think(hovered, selected, selectable bool, dt int64) {
+  if selectable && hovered && !ro.was_over {
+    sound.PlaySound("Haunts/SFX/UI/Tick", 0.75)
+  }
+  ro.was_over = hovered
+  if ro.was_selected != selected {
+    sound.PlaySound("Haunts/SFX/UI/Select", 0.75)
+  }
+  ro.was_selected = selected
+  switch {
+  case selected:
+    ro.alpha = 255
+  case selectable && hovered:
+    ro.alpha = 200
+  case selectable && !hovered:
+    ro.alpha = 150
+  default:
+    ro.alpha = 50
+  }
+}
+
+// Returns an option for every replay in the replay directory that we can
+// read.
+func replayOptions() []Option {
+  infos, err := ioutil.ReadDir(replayDir())
+  if err != nil && !os.IsNotExist(err) {
+    base.Warn().Printf("Unable to list replays: %v", err)
+  }
+  var opts []Option
+  for _, info := range infos {
+    if info.IsDir() || filepath.Ext(info.Name()) != replay_extension {
+      continue
+    }
+    path := filepath.Join(replayDir(), info.Name())
+    f, err := os.Open(path)
+    if err != nil {
+      base.Warn().Printf("Unable to open replay %s: %v", path, err)
+      continue
+    }
+    header, err := mrgnet.ReadReplayHeader(f)
+    f.Close()
+    if err != nil {
+      base.Warn().Printf("Unable to read replay %s: %v", path, err)
+      continue
+    }
+    opts = append(opts, &replayOption{path: path, header: header, size: 15})
+  }
+  return opts
+}
+
+// Lets the player pick one of their saved replays and watch it.
+func InsertReplayChooser(ui gui.WidgetParent) error {
+  chooser, done, err := MakeChooser(replayOptions())
+  if err != nil {
+    return err
+  }
+  ui.AddChild(chooser)
+  go func() {
+    m := <-done
+    ui.RemoveChild(chooser)
+    if m == nil || len(m) != 1 {
+      if err := InsertStartMenu(ui); err != nil {
+        base.Error().Printf("Unable to make start menu: %v", err)
+      }
+      return
+    }
+    replay, err := LoadReplay(m[0])
+    if err != nil {
+      base.Error().Printf("Unable to load replay %s: %v", m[0], err)
+      if err := InsertStartMenu(ui); err != nil {
+        base.Error().Printf("Unable to make start menu: %v", err)
+      }
+      return
+    }
+    ui.AddChild(MakeReplayPanel(replay))
+  }()
+  return nil
+}
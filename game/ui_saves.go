@@ -0,0 +1,142 @@
+package game
+
+import (
+  "fmt"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/sound"
+  "github.com/MobRulesGames/opengl/gl"
+)
+
+// An Option for the save chooser, and for the chooser that comes after it
+// that asks what to do with the chosen save.
+type saveOption struct {
+  id    string
+  label string
+  info  SaveInfo
+  size  int
+
+  alpha        byte
+  was_over     bool
+  was_selected bool
+}
+
+func (so *saveOption) String() string {
+  return so.id
+}
+func (so *saveOption) Draw(x, y, dx int) {
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, gl.Ubyte(so.alpha))
+  d := base.GetDictionary(so.size)
+  d.RenderString(so.label, float64(x), float64(y), 0, d.MaxHeight(), gui.Left)
+}
+func (so *saveOption) DrawInfo(x, y, dx, dy int) {
+  gl.Disable(gl.TEXTURE_2D)
+  gl.Color4ub(255, 255, 255, 255)
+  info := so.info
+  lines := []string{
+    info.Name,
+    fmt.Sprintf("Level: %s", info.Script),
+    fmt.Sprintf("Round %d, %s", info.Round, info.Side),
+    fmt.Sprintf("Saved %s", info.Time.Format("Jan 2 2006 15:04")),
+  }
+  d := base.GetDictionary(so.size)
+  ty := float64(y + dy)
+  for _, line := range lines {
+    ty -= d.MaxHeight()
+    d.RenderString(line, float64(x), ty, 0, d.MaxHeight(), gui.Left)
+  }
+}
+func (so *saveOption) Height() int {
+  return int(base.GetDictionary(so.size).MaxHeight())
+}
+func (so *saveOption) Think(hovered, selected, selectable bool, dt int64) {
+  if selectable && hovered && !so.was_over {
+    sound.PlaySound("Haunts/SFX/UI/Tick", 0.75)
+  }
+  so.was_over = hovered
+  if so.was_selected != selected {
+    sound.PlaySound("Haunts/SFX/UI/Select", 0.75)
+  }
+  so.was_selected = selected
+  switch {
+  case selected:
+    so.alpha = 255
+  case selectable && hovered:
+    so.alpha = 200
+  case selectable && !hovered:
+    so.alpha = 150
+  default:
+    so.alpha = 50
+  }
+}
+
+// Lets the player pick one of their saves and then either resume or delete
+// it.
+func InsertSaveChooser(ui gui.WidgetParent) error {
+  saves := ListSaves()
+  var opts []Option
+  for _, save := range saves {
+    opts = append(opts, &saveOption{id: save.Path(), label: save.Name, info: save, size: 15})
+  }
+  chooser, done, err := MakeChooser(opts)
+  if err != nil {
+    return err
+  }
+  ui.AddChild(chooser)
+  go func() {
+    m := <-done
+    ui.RemoveChild(chooser)
+    if m == nil || len(m) != 1 {
+      if err := InsertStartMenu(ui); err != nil {
+        base.Error().Printf("Unable to make start menu: %v", err)
+      }
+      return
+    }
+    for _, save := range saves {
+      if save.Path() == m[0] {
+        if err := insertSaveActionChooser(ui, save); err != nil {
+          base.Error().Printf("Unable to make save action chooser: %v", err)
+        }
+        return
+      }
+    }
+  }()
+  return nil
+}
+
+func insertSaveActionChooser(ui gui.WidgetParent, save SaveInfo) error {
+  opts := []Option{
+    &saveOption{id: "resume", label: "Resume", info: save, size: 15},
+    &saveOption{id: "delete", label: "Delete", info: save, size: 15},
+  }
+  chooser, done, err := MakeChooser(opts)
+  if err != nil {
+    return err
+  }
+  ui.AddChild(chooser)
+  go func() {
+    m := <-done
+    ui.RemoveChild(chooser)
+    if m != nil && len(m) == 1 {
+      switch m[0] {
+      case "resume":
+        player, err := LoadSave(save.Path())
+        if err == nil {
+          ui.AddChild(MakeGamePanel("", player, nil, ""))
+          return
+        }
+        base.Error().Printf("Unable to load save %s: %v", save.Path(), err)
+
+      case "delete":
+        if err := DeleteSave(save.Path()); err != nil {
+          base.Error().Printf("Unable to delete save %s: %v", save.Path(), err)
+        }
+      }
+    }
+    if err := InsertSaveChooser(ui); err != nil {
+      base.Error().Printf("Unable to make save chooser: %v", err)
+    }
+  }()
+  return nil
+}
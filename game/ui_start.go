@@ -16,6 +16,8 @@ type startLayout struct {
     Credits  Button
     Versus   Button
     Online   Button
+    Replays  Button
+    Saves    Button
     Settings Button
   }
   Background texture.Object
@@ -40,6 +42,8 @@ func InsertStartMenu(ui gui.WidgetParent) error {
     &sm.layout.Menu.Credits,
     &sm.layout.Menu.Versus,
     &sm.layout.Menu.Online,
+    &sm.layout.Menu.Replays,
+    &sm.layout.Menu.Saves,
     &sm.layout.Menu.Settings,
   }
   sm.layout.Menu.Credits.f = func(interface{}) {
@@ -73,6 +77,22 @@ func InsertStartMenu(ui gui.WidgetParent) error {
       return
     }
   }
+  sm.layout.Menu.Replays.f = func(interface{}) {
+    ui.RemoveChild(&sm)
+    err := InsertReplayChooser(ui)
+    if err != nil {
+      base.Error().Printf("Unable to make Replay Chooser: %v", err)
+      return
+    }
+  }
+  sm.layout.Menu.Saves.f = func(interface{}) {
+    ui.RemoveChild(&sm)
+    err := InsertSaveChooser(ui)
+    if err != nil {
+      base.Error().Printf("Unable to make Save Chooser: %v", err)
+      return
+    }
+  }
   ui.AddChild(&sm)
   return nil
 }
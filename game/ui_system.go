@@ -57,19 +57,16 @@ func MakeSystemMenu(gp *GamePanel, player *Player) (gui.Widget, error) {
 
   sm.layout.Sub.Save.Entry.text = player.Name
   sm.layout.Sub.Save.Button.f = func(interface{}) {
-    UpdatePlayer(player, gp.script.L)
-    str, err := base.ToGobToBase64(gp.game)
-    if err != nil {
-      base.Error().Printf("Error gobbing game state: %v", err)
+    name := sm.layout.Sub.Save.Text()
+    if name == "" {
       return
     }
-    player.Game_state = str
-    player.Name = sm.layout.Sub.Save.Text()
-    player.No_init = true
-    base.Log().Printf("Saving player: %v", player)
-    err = SavePlayer(player)
+    // Remembered so that the entry starts with it the next time.
+    player.Name = name
+    base.Log().Printf("Saving game to slot '%s'", name)
+    err := gp.script.saveToSlot(gp.game, name, false)
     if err != nil {
-      base.Warn().Printf("Unable to save player: %v", err)
+      base.Warn().Printf("Unable to save game: %v", err)
       return
     }
     sm.saved_time = time.Now()
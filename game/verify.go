@@ -0,0 +1,107 @@
+package game
+
+import (
+  "errors"
+  "fmt"
+  "github.com/MobRulesGames/glop/gui"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "runtime"
+  "strings"
+  "sync"
+  "time"
+)
+
+// How far a turn being verified is advanced every time it thinks, and how
+// long it can take before we give up on it.
+const verify_dt = 10
+const verify_timeout = 30 * time.Second
+
+// Checks turns uploaded to the server by replaying them headless, the same
+// way the other player's client will, and comparing the result with the
+// state that the uploader said the turn ended in.  This satisfies the mrgnet
+// server's Verifier interface.  base.SetHeadless(true) must have been called,
+// and everything loaded as in SetupHeadless, before it is used.
+type TurnVerifier struct {
+  // Turns are verified one at a time, the registries that every game loads
+  // from aren't meant to be shared between games that are running at once.
+  mutex sync.Mutex
+}
+
+func (v *TurnVerifier) VerifyTurn(game *mrgnet.Game, before, execs, after []byte) error {
+  v.mutex.Lock()
+  defer v.mutex.Unlock()
+  if game.Script == nil {
+    return errors.New("Can't verify a turn for a game that doesn't have a script.")
+  }
+  ours, err := replayTurn(game.Script, before, execs)
+  if err != nil {
+    return err
+  }
+  var g *Game
+  if _, err := decodeGameState(string(after), &g); err != nil {
+    return fmt.Errorf("Unable to decode the state after the turn: %v", err)
+  }
+  defer releaseGame(g)
+  diff := diffSummaries(ours, g.stateSummary())
+  if len(diff) > 0 {
+    return fmt.Errorf("The turn doesn't lead to the state that was uploaded with it:\n%s", strings.Join(diff, "\n"))
+  }
+  return nil
+}
+
+// Plays execs from before with script, just like the replay viewer would,
+// and returns the summary of the state that the game ends up in.
+func replayTurn(script, before, execs []byte) ([]string, error) {
+  var gp GamePanel
+  gp.AnchorBox = gui.MakeAnchorBox(gui.Dims{1024, 768})
+  gp.input = AutoInput{}
+  startReplayScript(&gp, &mrgnet.Replay{
+    Name:   "verify",
+    Script: script,
+    Before: [][]byte{before},
+    Execs:  [][]byte{execs},
+  })
+  rp := gp.script.replay
+  rp.stepped = make(chan bool, 1)
+  h := &HeadlessGame{gp: &gp}
+  h.SetClock(StepClock{verify_dt})
+  h.SetFastForward(true)
+  defer func() {
+    // If the script is stuck there's nothing we can do about it, but it
+    // shouldn't hold us up as well.
+    select {
+    case rp.commands <- replayCommand{kind: replayQuit}:
+    default:
+    }
+    h.Close()
+  }()
+
+  // Every step plays one exec, the step after the last one doesn't play
+  // anything.
+  start := time.Now()
+  for {
+    rp.commands <- replayCommand{kind: replayStep}
+    var played bool
+    stepped := false
+    for !stepped {
+      if time.Since(start) > verify_timeout {
+        return nil, errors.New("Timed out replaying the turn.")
+      }
+      h.Think(verify_dt)
+      // The script runs in its own go routine, it needs a chance to catch up.
+      runtime.Gosched()
+      select {
+      case played = <-rp.stepped:
+        stepped = true
+      default:
+      }
+    }
+    if !played {
+      break
+    }
+  }
+  if h.Game() == nil {
+    return nil, errors.New("Unable to load the state before the turn.")
+  }
+  return h.Game().stateSummary(), nil
+}
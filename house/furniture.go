@@ -101,6 +101,11 @@ type furnitureDef struct {
   // of furniture blocks los, then the entire piece blocks los, regardless of
   // orientation.
   Blocks_los bool
+
+  // Bonus to defense that an entity gets against ranged attacks that pass
+  // over this piece of furniture.  Furniture that blocks los doesn't need
+  // this since nothing can be attacked through it.
+  Cover int
 }
 
 func (f *Furniture) Dims() (int, int) {
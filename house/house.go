@@ -108,6 +108,10 @@ type doorDef struct {
   // never draws a threshold.
   Always_open bool
 
+  // Bonus to defense that an entity gets against ranged attacks that pass
+  // through this door's frame.
+  Cover int
+
   Opened_texture texture.Object
   Closed_texture texture.Object
 
@@ -3,6 +3,7 @@ package house
 import (
   "runtime"
   "github.com/MobRulesGames/glop/render"
+  "github.com/MobRulesGames/haunts/base"
   "github.com/MobRulesGames/opengl/gl"
 )
 
@@ -43,6 +44,11 @@ func MakeLosTexture() *LosTexture {
     lt.p2d[i] = lt.pix[i*LosTextureSize : (i+1)*LosTextureSize]
   }
 
+  // Headless games only need the pixels.
+  if base.Headless() {
+    return &lt
+  }
+
   render.Queue(func() {
     gl.Enable(gl.TEXTURE_2D)
     tex := gl.GenTexture()
@@ -77,7 +83,7 @@ func (lt *LosTexture) ready() bool {
 // Updates OpenGl with any changes that have been made to the texture.
 // OpenGl calls in this method are run on the render thread
 func (lt *LosTexture) Remap() {
-  if !lt.ready() {
+  if base.Headless() || !lt.ready() {
     return
   }
   render.Queue(func() {
@@ -1,13 +1,13 @@
 package main
 
 import (
+  "flag"
   "fmt"
   "os"
   "path/filepath"
   "runtime"
   "runtime/debug"
   "runtime/pprof"
-  "math/rand"
   gl "github.com/MobRulesGames/gogl/gl21"
   "github.com/MobRulesGames/glop/gin"
   "github.com/MobRulesGames/glop/gos"
@@ -19,6 +19,7 @@ import (
   "github.com/MobRulesGames/haunts/game"
   "github.com/MobRulesGames/haunts/sound"
   "github.com/MobRulesGames/haunts/house"
+  "github.com/MobRulesGames/haunts/mrgnet"
 
   // Need to pull in all of the actions we define here and not in
   // haunts/game because haunts/game/actions depends on it
@@ -44,6 +45,14 @@ var (
   zooming, dragging, hiding bool
 )
 
+var flags = flag.NewFlagSet("haunts", flag.ContinueOnError)
+
+func init() {
+  flags.StringVar(&game.Net_flags.Server_url, "server", "", "Url of the server to use for online games.")
+  flags.StringVar(&game.Net_flags.Ca_certs, "server-ca", "", "PEM files with additional certificates to trust when connecting to the server.")
+  flags.DurationVar(&game.Net_flags.Timeout, "server-timeout", 0, "Maximum time to wait on any single request to the server.")
+}
+
 func loadAllRegistries() {
   house.LoadAllFurnitureInDir(filepath.Join(datadir, "furniture"))
   house.LoadAllWallTexturesInDir(filepath.Join(datadir, "textures"))
@@ -59,7 +68,6 @@ func init() {
   runtime.LockOSThread()
   sys = system.Make(gos.GetSystemInterface())
 
-  rand.Seed(100)
   // TODO: This should not be OS-specific
   datadir = filepath.Join(os.Args[0], "..", "..")
   base.SetDatadir(datadir)
@@ -188,6 +196,53 @@ func editMode() {
   }
 }
 
+// Paths given to console commands are relative to the data directory.
+func consolePath(path string) string {
+  if filepath.IsAbs(path) {
+    return path
+  }
+  return filepath.Join(datadir, path)
+}
+
+func registerConsoleCommands() {
+  base.RegisterConsoleCommand("export-state", func(args []string) {
+    if len(args) != 1 || game_panel == nil {
+      base.Warn().Printf("Usage: export-state <file>, while a game is running.")
+      return
+    }
+    path := consolePath(args[0])
+    f, err := os.Create(path)
+    if err != nil {
+      base.Error().Printf("Unable to create %s: %v", path, err)
+      return
+    }
+    defer f.Close()
+    if err := game_panel.ExportState(f); err != nil {
+      base.Error().Printf("Unable to export state: %v", err)
+      return
+    }
+    base.Log().Printf("Exported state to %s", path)
+  })
+  base.RegisterConsoleCommand("import-state", func(args []string) {
+    if len(args) != 1 || game_panel == nil {
+      base.Warn().Printf("Usage: import-state <file>, while a game is running.")
+      return
+    }
+    path := consolePath(args[0])
+    f, err := os.Open(path)
+    if err != nil {
+      base.Error().Printf("Unable to open %s: %v", path, err)
+      return
+    }
+    defer f.Close()
+    if err := game_panel.ImportState(f); err != nil {
+      base.Error().Printf("Unable to import state: %v", err)
+      return
+    }
+    base.Log().Printf("Imported state from %s", path)
+  })
+}
+
 type lowerLeftTable struct {
   *gui.AnchorBox
 }
@@ -207,6 +262,15 @@ func main() {
     }
   }()
   base.Log().Printf("Version %s", Version())
+  mrgnet.Client_version = Version()
+  // Some platforms pass their own arguments when launching us, so we don't
+  // want to exit just because we didn't understand something.
+  if err := flags.Parse(os.Args[1:]); err != nil {
+    base.Warn().Printf("Unable to parse command line: %v", err)
+  }
+  if err := game.ConfigureNet(); err != nil {
+    base.Error().Printf("Unable to configure server: %v", err)
+  }
   sys.Startup()
   sound.Init()
   render.Init()
@@ -264,6 +328,7 @@ func main() {
 
   if base.IsDevel() {
     ui.AddChild(base.MakeConsole())
+    registerConsoleCommands()
   }
   sys.Think()
   // Wait until now to create the dictionary because the render thread needs
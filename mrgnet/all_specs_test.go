@@ -0,0 +1,12 @@
+package mrgnet_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  "testing"
+)
+
+func TestAllSpecs(t *testing.T) {
+  r := gospec.NewRunner()
+  r.AddSpec(ReplaySpec)
+  gospec.MainGoTest(r, t)
+}
@@ -0,0 +1,118 @@
+package mrgnet
+
+import (
+  "bytes"
+  "crypto/ed25519"
+  "crypto/rand"
+  "encoding/base64"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+)
+
+// An Identity is a NetId along with the key that proves we own it.  Every
+// request is signed with the key and the server binds a NetId to the first
+// key that signs for it, so knowing someone's NetId is no longer enough to
+// act on their behalf.
+type Identity struct {
+  Id  NetId
+  Key ed25519.PrivateKey
+}
+
+// Makes an identity with a new key.  If id is zero a new random NetId is
+// used, otherwise id is kept, which is how a player from before identities
+// existed migrates their NetId.
+func MakeIdentity(id NetId) (*Identity, error) {
+  if id == 0 {
+    id = RandomId()
+  }
+  _, key, err := ed25519.GenerateKey(rand.Reader)
+  if err != nil {
+    return nil, err
+  }
+  return &Identity{Id: id, Key: key}, nil
+}
+
+const identity_header = "haunts identity v1"
+
+// Returns the identity in a text format suitable for backing it up or
+// moving it to another machine.  Anyone with this text can act as this
+// player, so it should be treated like a password.
+func (ident *Identity) Marshal() string {
+  seed := base64.StdEncoding.EncodeToString(ident.Key.Seed())
+  return fmt.Sprintf("%s\n%d\n%s\n", identity_header, ident.Id, seed)
+}
+
+// Parses an identity that was produced by Identity.Marshal.
+func UnmarshalIdentity(text string) (*Identity, error) {
+  lines := strings.Fields(strings.Replace(text, identity_header, "", 1))
+  if !strings.HasPrefix(strings.TrimSpace(text), identity_header) || len(lines) != 2 {
+    return nil, errors.New("Not a valid identity.")
+  }
+  var ident Identity
+  _, err := fmt.Sscanf(lines[0], "%d", &ident.Id)
+  if err != nil || ident.Id == 0 {
+    return nil, errors.New("Not a valid identity: bad id.")
+  }
+  seed, err := base64.StdEncoding.DecodeString(lines[1])
+  if err != nil || len(seed) != ed25519.SeedSize {
+    return nil, errors.New("Not a valid identity: bad key.")
+  }
+  ident.Key = ed25519.NewKeyFromSeed(seed)
+  return &ident, nil
+}
+
+// Requests whose timestamps are further than this from the server's clock
+// are rejected, this limits how long a captured request can be replayed.
+const Max_clock_skew = 5 * time.Minute
+
+// The bytes that are signed for an envelope.  Everything that affects how
+// the server interprets the request is included.
+func (env *Envelope) signedBytes(action string) []byte {
+  buf := bytes.NewBuffer(nil)
+  buf.WriteString(action)
+  buf.WriteByte(0)
+  binary.Write(buf, binary.BigEndian, int64(env.Protocol))
+  binary.Write(buf, binary.BigEndian, int64(env.Id))
+  binary.Write(buf, binary.BigEndian, env.Timestamp)
+  buf.Write(env.Key)
+  buf.Write(env.Payload)
+  return buf.Bytes()
+}
+
+// Signs the envelope, which must already have its payload, as a request for
+// action.
+func (ident *Identity) Sign(env *Envelope, action string) {
+  env.Id = ident.Id
+  env.Timestamp = time.Now().UnixNano()
+  env.Key = ident.Key.Public().(ed25519.PublicKey)
+  env.Signature = ed25519.Sign(ident.Key, env.signedBytes(action))
+}
+
+// Returns true iff the envelope was signed for action by the key in
+// env.Key.  It is up to the caller to check that env.Key actually belongs to
+// env.Id.
+func (env *Envelope) Verify(action string) bool {
+  if len(env.Key) != ed25519.PublicKeySize || len(env.Signature) != ed25519.SignatureSize {
+    return false
+  }
+  return ed25519.Verify(ed25519.PublicKey(env.Key), env.signedBytes(action), env.Signature)
+}
+
+var identity *Identity
+
+// Sets the identity used to sign all subsequent requests made by DoAction.
+func SetIdentity(ident *Identity) {
+  config_mutex.Lock()
+  defer config_mutex.Unlock()
+  identity = ident
+}
+
+// Returns the identity set with SetIdentity, or nil if none has been set.
+func GetIdentity() *Identity {
+  config_mutex.Lock()
+  defer config_mutex.Unlock()
+  return identity
+}
@@ -2,65 +2,160 @@ package mrgnet
 
 import (
   "bytes"
-  "compress/gzip"
+  "context"
   "crypto/rand"
-  "encoding/gob"
+  "crypto/tls"
+  "crypto/x509"
   "fmt"
   "io"
   "io/ioutil"
   "math/big"
   "net/http"
   "net/url"
+  "strings"
+  "sync"
   "time"
 )
 
 type NetId int64
 type GameKey string
 
-const Host_url = "http://mobrulesgames.appspot.com/"
+const Default_host_url = "http://mobrulesgames.appspot.com/"
+const Default_timeout = 10 * time.Second
 
-// const Host_url = "http://localhost:8080"
+// Config specifies which server DoAction talks to and how.
+type Config struct {
+  // Base url of the server, e.g. "http://localhost:8080/".  If empty then
+  // Default_host_url is used.
+  Host_url string
 
-func DoAction(name string, input, output interface{}) error {
-  zipit := true
-  buf := bytes.NewBuffer(nil)
-  var gzw io.Writer
-  if zipit {
-    gzw = gzip.NewWriter(buf)
-  } else {
-    gzw = buf
+  // Maximum amount of time a single action can take, including reading the
+  // response.  If zero then Default_timeout is used.
+  Timeout time.Duration
+
+  // Paths to PEM encoded certificates that are trusted in addition to the
+  // system's roots.  This lets us talk to an https server that uses a
+  // self-signed certificate.
+  Ca_certs []string
+}
+
+var config_mutex sync.Mutex
+var config = Config{Host_url: Default_host_url, Timeout: Default_timeout}
+var client = &http.Client{}
+
+// Sets the server and transport used by all subsequent calls to DoAction.  If
+// an error is returned the previous configuration is left in place.
+func Configure(c Config) error {
+  if c.Host_url == "" {
+    c.Host_url = Default_host_url
+  }
+  if c.Timeout == 0 {
+    c.Timeout = Default_timeout
   }
-  err := gob.NewEncoder(gzw).Encode(input)
+  u, err := url.Parse(c.Host_url)
   if err != nil {
     return err
   }
-  if zipit {
-    gzw.(*gzip.Writer).Close()
+  if u.Scheme != "http" && u.Scheme != "https" {
+    return fmt.Errorf("Server url must be http or https, not '%s'.", c.Host_url)
+  }
+  transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+  if len(c.Ca_certs) > 0 {
+    pool, err := x509.SystemCertPool()
+    if err != nil || pool == nil {
+      pool = x509.NewCertPool()
+    }
+    for _, path := range c.Ca_certs {
+      pem, err := ioutil.ReadFile(path)
+      if err != nil {
+        return err
+      }
+      if !pool.AppendCertsFromPEM(pem) {
+        return fmt.Errorf("No certificates found in %s.", path)
+      }
+    }
+    transport.TLSClientConfig = &tls.Config{RootCAs: pool}
   }
-  host_url := fmt.Sprintf("%s/%s", Host_url, name)
+  config_mutex.Lock()
+  defer config_mutex.Unlock()
+  config = c
+  client = &http.Client{Transport: transport}
+  return nil
+}
+
+// Returns the configuration most recently set with Configure.
+func GetConfig() Config {
+  config_mutex.Lock()
+  defer config_mutex.Unlock()
+  return config
+}
+
+// Sends input to the server as the named action and decodes the response
+// into output.  Besides network errors this can return a *VersionError,
+// *DecodeError or *ServerError.
+func DoAction(name string, input, output interface{}) error {
+  return doAction(name, input, output, 0)
+}
+
+// Like DoAction, but the server is allowed to hold on to the request for up
+// to wait before responding, on top of the usual timeout.
+func DoLongPoll(name string, input, output interface{}, wait time.Duration) error {
+  return doAction(name, input, output, wait)
+}
+
+func doAction(name string, input, output interface{}, wait time.Duration) error {
+  req := Envelope{Protocol: Protocol_version, Client_version: Client_version}
+  err := req.Encode(input)
+  if err != nil {
+    return err
+  }
+  if ident := GetIdentity(); ident != nil {
+    ident.Sign(&req, name)
+  }
+  buf := bytes.NewBuffer(nil)
+  err = WriteEnvelope(buf, req)
+  if err != nil {
+    return err
+  }
+  config_mutex.Lock()
+  host_url := fmt.Sprintf("%s/%s", strings.TrimRight(config.Host_url, "/"), name)
+  c := client
+  timeout := config.Timeout + wait
+  config_mutex.Unlock()
+  ctx, cancel := context.WithTimeout(context.Background(), timeout)
+  defer cancel()
   // fmt.Printf("Sending %d bytes\n", buf.Len())
-  r, err := http.PostForm(host_url, url.Values{"data": []string{string(buf.Bytes())}})
+  form := url.Values{"data": []string{string(buf.Bytes())}}
+  hreq, err := http.NewRequestWithContext(ctx, "POST", host_url, strings.NewReader(form.Encode()))
+  if err != nil {
+    return err
+  }
+  hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  r, err := c.Do(hreq)
   if err != nil {
     return err
   }
+  defer r.Body.Close()
 
-  var gzr io.Reader
-  if zipit {
-    gzr, err = gzip.NewReader(r.Body)
-    if err != nil {
-      panic(err.Error())
-      return nil
-    }
-  } else {
-    gzr = r.Body
+  if r.StatusCode != http.StatusOK {
+    msg, _ := ioutil.ReadAll(io.LimitReader(r.Body, 1024))
+    return &ServerError{Action: name, Status: r.StatusCode, Msg: strings.TrimSpace(string(msg))}
+  }
+  resp, err := ReadEnvelope(r.Body)
+  if err != nil {
+    return &DecodeError{Action: name, Err: err}
+  }
+  if resp.Protocol != Protocol_version {
+    return &VersionError{Client: Protocol_version, Server: resp.Protocol}
+  }
+  if resp.Err != "" {
+    return &ServerError{Action: name, Status: r.StatusCode, Msg: resp.Err}
   }
-  data, err := ioutil.ReadAll(gzr)
+  err = resp.Decode(output)
   if err != nil {
-    panic(err.Error())
-    return nil
+    return &DecodeError{Action: name, Err: err}
   }
-  dec := gob.NewDecoder(bytes.NewBuffer(data))
-  return dec.Decode(output)
+  return nil
 }
 
 // Creates a random id that will be unique among all other engines with high
@@ -78,6 +173,10 @@ func RandomId() NetId {
 type User struct {
   Id   NetId
   Name string
+
+  // The public key of the Identity that owns Id.  The server sets this the
+  // first time it sees a signed request for Id.
+  Key []byte
 }
 
 type UpdateUserRequest User
@@ -88,6 +187,10 @@ type UpdateUserResponse struct {
 
 type NewGameRequest struct {
   Id NetId
+
+  // How long each player has to take their turn, zero means there is no
+  // deadline.
+  Turn_deadline time.Duration
 }
 
 type NewGameResponse struct {
@@ -99,6 +202,10 @@ type NewGameResponse struct {
 type ListGamesRequest struct {
   Id        NetId
   Unstarted bool
+
+  // If set, lists games in progress that Id isn't playing in and can
+  // spectate instead, Unstarted is ignored.
+  Spectate bool
 }
 
 type ListGamesResponse struct {
@@ -120,6 +227,13 @@ type UpdateGameRequest struct {
   Execs  []byte
   After  []byte
   Script []byte
+
+  // Sent along with Execs, a hash of the gameplay-relevant parts of After
+  // and the summary it was computed from.  The other player checks that
+  // replaying Execs gets them the same hash, and uses the summary to work
+  // out what went wrong if it doesn't.
+  Checksum []byte
+  Summary  []byte
 }
 
 type UpdateGameResponse struct {
@@ -147,6 +261,86 @@ type StatusResponse struct {
   Game *Game
 }
 
+// How long a client should ask the server to hold on to a WaitRequest.
+const Long_poll_duration = 30 * time.Second
+
+// Blocks until the game has at least Turns entries in both Before and Execs,
+// until the game is over, or until Timeout has passed, whichever comes
+// first.  Use with DoLongPoll.
+type WaitRequest struct {
+  Id       NetId
+  Game_key GameKey
+  Turns    int
+  Timeout  time.Duration
+}
+
+type WaitResponse struct {
+  Err string
+
+  // True if the game has reached the requested number of turns, or is over,
+  // false if the request timed out first.
+  Ready bool
+
+  // If Ready this is the game with only the sizes of the playback data, as
+  // with StatusRequest.Sizes_only.
+  Game *Game
+}
+
+// Queues Id to be matched with another player by the server.  Empty
+// preferences match anything.  Like WaitRequest this blocks until a match is
+// found or Timeout passes, and should be sent again until Matched is set.
+// Use with DoLongPoll.
+type MatchRequest struct {
+  Id NetId
+
+  // "Denizens", "Intruders" or "".
+  Side string
+
+  // The level script, as chosen from ui/start/versus/map_select.json.
+  Map string
+
+  // The goal, as chosen from ui/start/versus/goals.json.
+  Goal string
+
+  // If set Id is removed from the queue instead.
+  Cancel bool
+
+  Timeout time.Duration
+}
+
+type MatchResponse struct {
+  Err string
+
+  // If Matched then Game_key is a game with both players already in it, and
+  // the rest are the settings that were agreed on.  The Denizens player is
+  // responsible for starting the game with Map.
+  Matched  bool
+  Game_key GameKey
+  Side     string
+  Map      string
+  Goal     string
+}
+
+// Sends a chat message to the other player in a game.  The response has all
+// of the chat messages in the game, including the new one.
+type ChatRequest struct {
+  Id       NetId
+  Game_key GameKey
+  Text     string
+}
+
+type ChatResponse struct {
+  Err  string
+  Chat map[int][]ChatMessage
+}
+
+type ChatMessage struct {
+  Id   NetId
+  Name string
+  Time time.Time
+  Text string
+}
+
 type KillRequest struct {
   Id       NetId
   Game_key GameKey
@@ -173,7 +367,23 @@ type Game struct {
   After  [][]byte
   Script []byte
 
+  // One for each of Execs, see UpdateGameRequest.  These are nil for turns
+  // that were uploaded without them.
+  Checksums [][]byte
+  Summaries [][]byte
+
   // If this is non-zero then the game is over and the winner is the player
   // whose NetId matches this value
   Winner NetId
+
+  // If non-zero each player must finish their turn within this long of the
+  // previous turn finishing, or of the game starting for the first turn.  A
+  // player that doesn't loses the game and Forfeit is set.
+  Turn_deadline time.Duration
+  Turn_started  time.Time
+  Forfeit       bool
+
+  // Chat messages between the players, keyed by the round they were sent
+  // during.  These are always sent in full, even with Sizes_only.
+  Chat map[int][]ChatMessage
 }
@@ -0,0 +1,130 @@
+package mrgnet
+
+import (
+  "bytes"
+  "compress/gzip"
+  "encoding/gob"
+  "fmt"
+  "io"
+  "net/http"
+  "strings"
+)
+
+// Version of the request and response types.  gob already tolerates fields
+// being added or removed, so this only needs to be bumped when a change is
+// made that an older client or server would misinterpret, like changing the
+// meaning or type of an existing field.
+const Protocol_version = 3
+
+// The version of the game that is using this package, this is sent along
+// with every request so that the server can log it.  main sets this.
+var Client_version string
+
+// Every request and response is wrapped in an Envelope, which is gobbed and
+// then gzipped.  The payload is the gobbed request or response itself, it is
+// kept separate so that the envelope can always be decoded, even when the
+// payload has drifted too far to be.
+type Envelope struct {
+  Protocol       int
+  Client_version string
+
+  // Only set in responses, if non-empty the server couldn't handle the
+  // request at all and Payload will be empty.
+  Err string
+
+  // Only set in requests, these identify the sender, see Identity.Sign.
+  Id        NetId
+  Timestamp int64
+  Key       []byte
+  Signature []byte
+
+  Payload []byte
+}
+
+// Returned by DoAction when the client and server speak different versions
+// of the protocol.
+type VersionError struct {
+  Client, Server int
+}
+
+func (e *VersionError) Error() string {
+  if e.Client < e.Server {
+    return fmt.Sprintf("This version of Haunts is too old for the server (protocol %d, server speaks %d), please update.", e.Client, e.Server)
+  }
+  return fmt.Sprintf("This version of Haunts is too new for the server (protocol %d, server speaks %d).", e.Client, e.Server)
+}
+
+// Returned by DoAction when a response couldn't be decoded.
+type DecodeError struct {
+  Action string
+  Err    error
+}
+
+func (e *DecodeError) Error() string {
+  return fmt.Sprintf("Unable to decode response to '%s': %v", e.Action, e.Err)
+}
+
+// Returned by DoAction when the server refused a request outright.  Failures
+// that are specific to a particular request are reported in the Err field of
+// that request's response instead.
+type ServerError struct {
+  Action string
+
+  // The http status code of the response.
+  Status int
+
+  Msg string
+}
+
+func (e *ServerError) Error() string {
+  return fmt.Sprintf("Server error on '%s' (%d): %s", e.Action, e.Status, e.Msg)
+}
+
+// What the server says when it gets a request for an action it doesn't know
+// about, followed by the name of the action.
+const Unknown_action = "Unknown action"
+
+// Returns true if the server refused the request because it doesn't know
+// about the action at all, which means the server is older than the client.
+func (e *ServerError) Unsupported() bool {
+  switch e.Status {
+  case http.StatusNotFound, http.StatusNotImplemented:
+    return true
+  }
+  return strings.HasPrefix(e.Msg, Unknown_action)
+}
+
+// Gobs v into the envelope's payload.
+func (env *Envelope) Encode(v interface{}) error {
+  buf := bytes.NewBuffer(nil)
+  err := gob.NewEncoder(buf).Encode(v)
+  if err != nil {
+    return err
+  }
+  env.Payload = buf.Bytes()
+  return nil
+}
+
+// Decodes the envelope's payload into v.
+func (env *Envelope) Decode(v interface{}) error {
+  return gob.NewDecoder(bytes.NewBuffer(env.Payload)).Decode(v)
+}
+
+func WriteEnvelope(w io.Writer, env Envelope) error {
+  gzw := gzip.NewWriter(w)
+  err := gob.NewEncoder(gzw).Encode(env)
+  if err != nil {
+    return err
+  }
+  return gzw.Close()
+}
+
+func ReadEnvelope(r io.Reader) (Envelope, error) {
+  var env Envelope
+  gzr, err := gzip.NewReader(r)
+  if err != nil {
+    return env, err
+  }
+  err = gob.NewDecoder(gzr).Decode(&env)
+  return env, err
+}
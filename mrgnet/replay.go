@@ -0,0 +1,134 @@
+package mrgnet
+
+import (
+  "compress/gzip"
+  "encoding/gob"
+  "fmt"
+  "io"
+  "time"
+)
+
+// Version of the replay file format, bumped whenever Replay changes in a way
+// that an older reader would misinterpret.
+const Replay_version = 1
+
+// The part of a replay that is needed to list it, this is written before the
+// rest of the replay so that it can be read without decoding everything.
+type ReplayHeader struct {
+  Version int
+
+  Name           string
+  Created        time.Time
+  Denizens_name  string
+  Intruders_name string
+
+  // "Denizens" or "Intruders", or empty if the game wasn't finished.
+  Winner string
+
+  // Number of turns in the replay, each side's turn counts separately.
+  Turns int
+
+  // The house that the level script plays in.
+  House string
+
+  // A hash of the game data that the game was played with, if the installed
+  // data doesn't match the replay might not play back the same way.
+  Data_checksum []byte
+}
+
+// A complete record of an online game, enough to watch it from start to
+// finish without talking to the server.
+type Replay struct {
+  ReplayHeader
+
+  Script []byte
+
+  // These are the same as in Game, one for each turn.
+  Before    [][]byte
+  Execs     [][]byte
+  After     [][]byte
+  Checksums [][]byte
+}
+
+// Makes a replay of everything that has happened in game so far.  The
+// House and Data_checksum are left for the caller to fill in since only the
+// game knows what they are.
+func MakeReplay(game *Game) *Replay {
+  var r Replay
+  r.Version = Replay_version
+  r.Name = game.Name
+  r.Created = game.Created
+  r.Denizens_name = game.Denizens_name
+  r.Intruders_name = game.Intruders_name
+  switch {
+  case game.Winner == 0:
+  case game.Winner == game.Denizens_id:
+    r.Winner = "Denizens"
+  default:
+    r.Winner = "Intruders"
+  }
+  // A game in progress already has the Before of the turn being played, the
+  // replay stops at the last turn that was finished.
+  r.Turns = len(game.Execs)
+  r.Script = game.Script
+  r.Before = firstTurns(game.Before, r.Turns)
+  r.Execs = game.Execs
+  r.After = firstTurns(game.After, r.Turns)
+  r.Checksums = firstTurns(game.Checksums, r.Turns)
+  return &r
+}
+
+// Returns the first n elements of turns, or all of them if there are fewer.
+func firstTurns(turns [][]byte, n int) [][]byte {
+  if len(turns) > n {
+    return turns[0:n]
+  }
+  return turns
+}
+
+func WriteReplay(w io.Writer, r *Replay) error {
+  gzw := gzip.NewWriter(w)
+  enc := gob.NewEncoder(gzw)
+  if err := enc.Encode(r.ReplayHeader); err != nil {
+    return err
+  }
+  if err := enc.Encode(r); err != nil {
+    return err
+  }
+  return gzw.Close()
+}
+
+func readReplay(r io.Reader, header_only bool) (*Replay, error) {
+  gzr, err := gzip.NewReader(r)
+  if err != nil {
+    return nil, err
+  }
+  var replay Replay
+  dec := gob.NewDecoder(gzr)
+  if err := dec.Decode(&replay.ReplayHeader); err != nil {
+    return nil, err
+  }
+  if replay.Version != Replay_version {
+    return nil, fmt.Errorf("Replay is version %d, this version of Haunts can only read version %d.", replay.Version, Replay_version)
+  }
+  if header_only {
+    return &replay, nil
+  }
+  if err := dec.Decode(&replay); err != nil {
+    return nil, err
+  }
+  return &replay, nil
+}
+
+// Reads just the header of a replay written by WriteReplay.
+func ReadReplayHeader(r io.Reader) (*ReplayHeader, error) {
+  replay, err := readReplay(r, true)
+  if err != nil {
+    return nil, err
+  }
+  return &replay.ReplayHeader, nil
+}
+
+func ReadReplay(r io.Reader) (*Replay, error) {
+  return readReplay(r, false)
+}
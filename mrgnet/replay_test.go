@@ -0,0 +1,49 @@
+package mrgnet_test
+
+import (
+  "bytes"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+func ReplaySpec(c gospec.Context) {
+  c.Specify("A replay of a game in progress stops at the last finished turn.", func() {
+    // Three turns have been played and the fourth has been started, so its
+    // Before is already on the server.
+    var game mrgnet.Game
+    game.Name = "Dracula vs. Van Helsing"
+    game.Script = []byte("script")
+    game.Before = [][]byte{[]byte("b0"), []byte("b1"), []byte("b2"), []byte("b3")}
+    game.Execs = [][]byte{[]byte("e0"), []byte("e1"), []byte("e2")}
+    game.After = [][]byte{[]byte("a0"), []byte("a1"), []byte("a2")}
+    game.Checksums = [][]byte{[]byte("c0"), []byte("c1"), []byte("c2")}
+
+    buf := bytes.NewBuffer(nil)
+    c.Assume(mrgnet.WriteReplay(buf, mrgnet.MakeReplay(&game)), Equals, nil)
+    replay, err := mrgnet.ReadReplay(buf)
+    c.Assume(err, Equals, nil)
+    c.Expect(replay.Name, Equals, game.Name)
+    c.Expect(replay.Winner, Equals, "")
+    c.Expect(replay.Turns, Equals, 3)
+    c.Expect(len(replay.Before), Equals, 3)
+    c.Expect(len(replay.Execs), Equals, 3)
+    c.Expect(len(replay.After), Equals, 3)
+    c.Expect(len(replay.Checksums), Equals, 3)
+    c.Expect(string(replay.Before[2]), Equals, "b2")
+    c.Expect(string(replay.Execs[2]), Equals, "e2")
+  })
+
+  c.Specify("Only the header is read by ReadReplayHeader.", func() {
+    var game mrgnet.Game
+    game.Name = "Dracula vs. Van Helsing"
+    game.Before = [][]byte{[]byte("b0")}
+    game.Execs = [][]byte{[]byte("e0")}
+    buf := bytes.NewBuffer(nil)
+    c.Assume(mrgnet.WriteReplay(buf, mrgnet.MakeReplay(&game)), Equals, nil)
+    header, err := mrgnet.ReadReplayHeader(buf)
+    c.Assume(err, Equals, nil)
+    c.Expect(header.Name, Equals, game.Name)
+    c.Expect(header.Turns, Equals, 1)
+  })
+}
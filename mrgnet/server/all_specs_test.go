@@ -0,0 +1,12 @@
+package server_test
+
+import (
+  "github.com/orfjackal/gospec/src/gospec"
+  "testing"
+)
+
+func TestAllSpecs(t *testing.T) {
+  r := gospec.NewRunner()
+  r.AddSpec(ServerSpec)
+  gospec.MainGoTest(r, t)
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+  "fmt"
+  "time"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// The map used when neither player in a match cares which map they play.
+const default_match_map = "Lvl01.lua"
+
+// Tickets that haven't been asked about in this long are dropped from the
+// queue, the player has presumably given up.
+const match_ticket_lifetime = 2 * max_wait
+
+// A player in the matchmaking queue.
+type matchTicket struct {
+  req       mrgnet.MatchRequest
+  last_poll time.Time
+
+  // Closed, and result filled in, once this ticket is matched.
+  ready  chan struct{}
+  result *mrgnet.MatchResponse
+}
+
+// Merges two preferences, returning false if they are incompatible.
+func mergePreference(a, b string) (string, bool) {
+  switch {
+  case a == "":
+    return b, true
+  case b == "" || a == b:
+    return a, true
+  }
+  return "", false
+}
+
+func otherSide(side string) string {
+  switch side {
+  case "Denizens":
+    return "Intruders"
+  case "Intruders":
+    return "Denizens"
+  }
+  return ""
+}
+
+func samePreferences(a, b mrgnet.MatchRequest) bool {
+  return a.Side == b.Side && a.Map == b.Map && a.Goal == b.Goal
+}
+
+// Returns the side that a will play if a and b can be matched, a is assumed
+// to have been waiting longer and gets the Denizens if neither of them cares.
+func matchSides(a, b mrgnet.MatchRequest) (string, bool) {
+  side, ok := mergePreference(a.Side, otherSide(b.Side))
+  if !ok {
+    return "", false
+  }
+  if side == "" {
+    side = "Denizens"
+  }
+  return side, true
+}
+
+func (s *Server) match(req mrgnet.MatchRequest) mrgnet.MatchResponse {
+  var resp mrgnet.MatchResponse
+  if req.Id == 0 {
+    resp.Err = "No user id specified."
+    return resp
+  }
+  if req.Side != "" && otherSide(req.Side) == "" {
+    resp.Err = fmt.Sprintf("Unknown side '%s'.", req.Side)
+    return resp
+  }
+  timeout := req.Timeout
+  if timeout > max_wait {
+    timeout = max_wait
+  }
+
+  s.mutex.Lock()
+  s.pruneQueue(time.Now())
+  ticket := s.findTicket(req.Id)
+  if req.Cancel {
+    if ticket != nil {
+      s.removeTicket(ticket)
+    }
+    s.mutex.Unlock()
+    return resp
+  }
+  if ticket == nil || (ticket.result == nil && !samePreferences(ticket.req, req)) {
+    if ticket != nil {
+      s.removeTicket(ticket)
+    }
+    ticket = &matchTicket{req: req, ready: make(chan struct{})}
+    if other := s.findOpponent(req); other != nil {
+      err := s.makeMatch(other, ticket)
+      if err != nil {
+        s.mutex.Unlock()
+        resp.Err = err.Error()
+        return resp
+      }
+    } else {
+      s.queue = append(s.queue, ticket)
+    }
+  }
+  ticket.last_poll = time.Now()
+  s.mutex.Unlock()
+
+  select {
+  case <-ticket.ready:
+  case <-time.After(timeout):
+  }
+
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  if ticket.result == nil {
+    return resp
+  }
+  s.removeTicket(ticket)
+  return *ticket.result
+}
+
+func (s *Server) findTicket(id mrgnet.NetId) *matchTicket {
+  for _, ticket := range s.queue {
+    if ticket.req.Id == id {
+      return ticket
+    }
+  }
+  return nil
+}
+
+func (s *Server) removeTicket(ticket *matchTicket) {
+  for i := range s.queue {
+    if s.queue[i] == ticket {
+      s.queue = append(s.queue[:i], s.queue[i+1:]...)
+      return
+    }
+  }
+}
+
+func (s *Server) pruneQueue(now time.Time) {
+  var queue []*matchTicket
+  for _, ticket := range s.queue {
+    if now.Sub(ticket.last_poll) < match_ticket_lifetime {
+      queue = append(queue, ticket)
+    }
+  }
+  s.queue = queue
+}
+
+// Returns the ticket that has been waiting longest that req can be matched
+// with, or nil if there isn't one.
+func (s *Server) findOpponent(req mrgnet.MatchRequest) *matchTicket {
+  for _, ticket := range s.queue {
+    if ticket.result != nil || ticket.req.Id == req.Id {
+      continue
+    }
+    if _, ok := matchSides(ticket.req, req); !ok {
+      continue
+    }
+    if _, ok := mergePreference(ticket.req.Map, req.Map); !ok {
+      continue
+    }
+    if _, ok := mergePreference(ticket.req.Goal, req.Goal); !ok {
+      continue
+    }
+    return ticket
+  }
+  return nil
+}
+
+// Makes a game for the two tickets, which must be compatible, and fills in
+// both of their results.  a is the ticket that was already queued.
+func (s *Server) makeMatch(a, b *matchTicket) error {
+  side, _ := matchSides(a.req, b.req)
+  mp, _ := mergePreference(a.req.Map, b.req.Map)
+  if mp == "" {
+    mp = default_match_map
+  }
+  goal, _ := mergePreference(a.req.Goal, b.req.Goal)
+
+  denizens, intruders := a.req.Id, b.req.Id
+  if side != "Denizens" {
+    denizens, intruders = intruders, denizens
+  }
+  d_user, err := s.getUser(denizens)
+  if err != nil {
+    return err
+  }
+  i_user, err := s.getUser(intruders)
+  if err != nil {
+    return err
+  }
+  key, err := s.store.NewGameKey()
+  if err != nil {
+    return err
+  }
+  var game mrgnet.Game
+  game.Name = fmt.Sprintf("%s vs. %s", d_user.Name, i_user.Name)
+  game.Created = time.Now()
+  game.Denizens_id = d_user.Id
+  game.Denizens_name = d_user.Name
+  game.Intruders_id = i_user.Id
+  game.Intruders_name = i_user.Name
+  game.Turn_started = game.Created
+  game.Turn_deadline = s.Match_deadline
+  err = s.store.PutGame(key, &game)
+  if err != nil {
+    return err
+  }
+  s.logf("Matched %d and %d in game %s", denizens, intruders, key)
+
+  for _, ticket := range []*matchTicket{a, b} {
+    ticket.result = &mrgnet.MatchResponse{
+      Matched:  true,
+      Game_key: key,
+      Side:     "Intruders",
+      Map:      mp,
+      Goal:     goal,
+    }
+    if ticket.req.Id == denizens {
+      ticket.result.Side = "Denizens"
+    }
+    close(ticket.ready)
+  }
+  return nil
+}
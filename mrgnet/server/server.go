@@ -0,0 +1,793 @@
+// Package server is a self-hostable implementation of the server side of the
+// mrgnet protocol.  It handles every action that mrgnet.DoAction sends and
+// keeps all of its data on local disk, so private matches and integration
+// tests don't need the hosted server.
+package server
+
+import (
+  "bytes"
+  "compress/gzip"
+  "encoding/gob"
+  "fmt"
+  "io/ioutil"
+  "log"
+  "net/http"
+  "net/url"
+  "strings"
+  "sync"
+  "time"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "unicode/utf8"
+)
+
+// Requests larger than this are rejected outright.  Game states are sent as
+// form values, which roughly triples the size of the gzipped data, so this
+// needs to be a good deal larger than the largest game state.
+const max_request_size = 64 << 20
+
+// The longest we'll hold on to a wait request, regardless of what the client
+// asks for.
+const max_wait = time.Minute
+
+// Chat messages longer than this many bytes are truncated.
+const max_chat_length = 500
+
+// Players that were matched up were both waiting for a game, so they get this
+// long to take each turn unless the server says otherwise.
+const default_match_deadline = 10 * time.Minute
+
+type Server struct {
+  store *Store
+
+  // All actions are serialized through this, nothing we do is expensive
+  // enough for that to matter.  Wait requests release it while they block.
+  mutex sync.Mutex
+
+  // Channels that are closed the next time the corresponding game changes,
+  // this is how wait requests find out that they can return.
+  watchers map[mrgnet.GameKey][]chan struct{}
+
+  // Players waiting to be matched with an opponent, oldest first.
+  queue []*matchTicket
+
+  // Signatures of recently accepted requests, and when they were accepted,
+  // so that a captured request can't be replayed.
+  seen       map[string]time.Time
+  last_prune time.Time
+
+  // If non-nil every request and any errors are logged here.
+  Log *log.Logger
+
+  // If non-nil every turn is checked with this before it is accepted.
+  Verifier Verifier
+
+  // How long each player has to take their turn in games made by matchmaking,
+  // a player that misses it forfeits.  Zero means there is no deadline.
+  Match_deadline time.Duration
+}
+
+func MakeServer(store *Store) *Server {
+  return &Server{
+    store:    store,
+    watchers: make(map[mrgnet.GameKey][]chan struct{}),
+    seen:     make(map[string]time.Time),
+
+    Match_deadline: default_match_deadline,
+  }
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+  if s.Log != nil {
+    s.Log.Printf(format, args...)
+  }
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  // Older clients send paths like "//new", so we trim the slashes ourselves
+  // rather than relying on http.ServeMux, which would redirect.
+  name := strings.Trim(r.URL.Path, "/")
+  if r.Method != "POST" {
+    http.Error(w, "Only POST is supported.", http.StatusMethodNotAllowed)
+    return
+  }
+  body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, max_request_size))
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+    return
+  }
+  values, err := url.ParseQuery(string(body))
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return
+  }
+  data := []byte(values.Get("data"))
+  s.logf("%s: %d bytes", name, len(data))
+
+  req, err := mrgnet.ReadEnvelope(bytes.NewBuffer(data))
+  if err != nil {
+    // Clients from before the protocol was versioned send their requests
+    // bare.  Every response has an Err field, so even without knowing which
+    // response they expect we can still tell them what is wrong.
+    s.logf("%s: Unable to read envelope: %v", name, err)
+    w.Write(legacyError)
+    return
+  }
+
+  resp := mrgnet.Envelope{Protocol: mrgnet.Protocol_version}
+  if req.Protocol != mrgnet.Protocol_version {
+    s.logf("%s: Client %q speaks protocol %d", name, req.Client_version, req.Protocol)
+    resp.Err = "Protocol mismatch."
+  } else {
+    payload, err := s.doAction(name, &req)
+    if err == nil {
+      err = resp.Encode(payload)
+    }
+    if err != nil {
+      s.logf("%s: %v", name, err)
+      resp.Err = err.Error()
+    }
+  }
+  buf := bytes.NewBuffer(nil)
+  err = mrgnet.WriteEnvelope(buf, resp)
+  if err != nil {
+    s.logf("%s: Unable to encode response: %v", name, err)
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+  w.Header().Set("Content-Type", "application/octet-stream")
+  w.Write(buf.Bytes())
+}
+
+var legacyError []byte
+
+func init() {
+  buf := bytes.NewBuffer(nil)
+  gzw := gzip.NewWriter(buf)
+  gob.NewEncoder(gzw).Encode(struct{ Err string }{"This version of Haunts is too old for the server, please update."})
+  gzw.Close()
+  legacyError = buf.Bytes()
+}
+
+// Decodes the request for the named action, runs it, and returns the
+// response that should be sent back.  An error is only returned if the
+// request couldn't be understood at all, game-level failures are reported
+// in the Err field of the response like the client expects.
+func (s *Server) doAction(name string, env *mrgnet.Envelope) (interface{}, error) {
+  // wait and match do their own locking since they have to release the lock
+  // while they block.
+  switch name {
+  case "wait":
+    var req mrgnet.WaitRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    s.mutex.Lock()
+    err := s.authenticate(name, env, req.Id)
+    s.mutex.Unlock()
+    if err != nil {
+      return nil, err
+    }
+    return s.wait(req), nil
+
+  case "match":
+    var req mrgnet.MatchRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    s.mutex.Lock()
+    err := s.authenticate(name, env, req.Id)
+    s.mutex.Unlock()
+    if err != nil {
+      return nil, err
+    }
+    return s.match(req), nil
+  }
+
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  switch name {
+  case "new":
+    var req mrgnet.NewGameRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.newGame(req), nil
+
+  case "list":
+    var req mrgnet.ListGamesRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.listGames(req), nil
+
+  case "user":
+    var req mrgnet.UpdateUserRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.updateUser(req), nil
+
+  case "update":
+    var req mrgnet.UpdateGameRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.updateGame(req), nil
+
+  case "join":
+    var req mrgnet.JoinGameRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.joinGame(req), nil
+
+  case "status":
+    var req mrgnet.StatusRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.status(req), nil
+
+  case "chat":
+    var req mrgnet.ChatRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.chat(req), nil
+
+  case "kill":
+    var req mrgnet.KillRequest
+    if err := env.Decode(&req); err != nil {
+      return nil, err
+    }
+    if err := s.authenticate(name, env, req.Id); err != nil {
+      return nil, err
+    }
+    return s.kill(req), nil
+  }
+  return nil, fmt.Errorf("%s '%s'.", mrgnet.Unknown_action, name)
+}
+
+// Checks that env was signed for action by the owner of id.  The first key
+// that signs for an id becomes its owner, this is how ids that were made
+// before requests were signed get claimed.  s.mutex must be held.
+func (s *Server) authenticate(action string, env *mrgnet.Envelope, id mrgnet.NetId) error {
+  if id == 0 {
+    // Nothing can be done without an id anyway, so the action itself will
+    // report the problem.
+    return nil
+  }
+  if env.Id != id {
+    return fmt.Errorf("Request for %d was signed by %d.", id, env.Id)
+  }
+  if !env.Verify(action) {
+    return fmt.Errorf("Invalid signature.")
+  }
+  now := time.Now()
+  skew := now.Sub(time.Unix(0, env.Timestamp))
+  if skew > mrgnet.Max_clock_skew || skew < -mrgnet.Max_clock_skew {
+    return fmt.Errorf("Request timestamp is too far from the server's clock.")
+  }
+  if now.Sub(s.last_prune) > mrgnet.Max_clock_skew {
+    for sig, t := range s.seen {
+      if now.Sub(t) > 2*mrgnet.Max_clock_skew {
+        delete(s.seen, sig)
+      }
+    }
+    s.last_prune = now
+  }
+  if _, ok := s.seen[string(env.Signature)]; ok {
+    return fmt.Errorf("Request has already been handled.")
+  }
+
+  user, err := s.getUser(id)
+  if err != nil {
+    return err
+  }
+  if user.Key == nil {
+    user.Key = env.Key
+    err = s.store.PutUser(user)
+    if err != nil {
+      return err
+    }
+    s.logf("Bound %d to a new key.", id)
+  } else if !bytes.Equal(user.Key, env.Key) {
+    return fmt.Errorf("Id %d belongs to someone else.", id)
+  }
+  s.seen[string(env.Signature)] = now
+  return nil
+}
+
+// Returns the user with the specified id, making and storing a new one if
+// this is the first we've heard of them.
+func (s *Server) getUser(id mrgnet.NetId) (*mrgnet.User, error) {
+  user, err := s.store.GetUser(id)
+  if err != nil || user != nil {
+    return user, err
+  }
+  user = &mrgnet.User{Id: id, Name: fmt.Sprintf("Player %d", id%10000)}
+  return user, s.store.PutUser(user)
+}
+
+// Loads a game, first ending it if the player whose turn it is has missed
+// the deadline.  Returns nil if there is no such game.
+func (s *Server) getGame(key mrgnet.GameKey) (*mrgnet.Game, error) {
+  game, err := s.store.GetGame(key)
+  if err != nil || game == nil {
+    return game, err
+  }
+  if expireDeadline(game, time.Now()) {
+    s.logf("Game %s was forfeited by the %s.", key, moverName(game))
+    err = s.store.PutGame(key, game)
+    if err != nil {
+      return nil, err
+    }
+    s.notify(key)
+  }
+  return game, nil
+}
+
+// Returns the NetId of the player whose turn it is.  Each round is a turn by
+// the denizens followed by one by the intruders.
+func mover(game *mrgnet.Game) mrgnet.NetId {
+  if len(game.Execs)%2 == 0 {
+    return game.Denizens_id
+  }
+  return game.Intruders_id
+}
+
+func moverName(game *mrgnet.Game) string {
+  if mover(game) == game.Denizens_id {
+    return "denizens"
+  }
+  return "intruders"
+}
+
+// If the player whose turn it is in game has missed its deadline the other
+// player is made the winner.  Returns true iff game was changed.
+func expireDeadline(game *mrgnet.Game, now time.Time) bool {
+  if game.Winner != 0 || game.Turn_deadline <= 0 || game.Intruders_id == 0 {
+    return false
+  }
+  if now.Sub(game.Turn_started) <= game.Turn_deadline {
+    return false
+  }
+  if mover(game) == game.Denizens_id {
+    game.Winner = game.Intruders_id
+  } else {
+    game.Winner = game.Denizens_id
+  }
+  game.Forfeit = true
+  return true
+}
+
+func (s *Server) updateUser(req mrgnet.UpdateUserRequest) mrgnet.UpdateUserResponse {
+  var resp mrgnet.UpdateUserResponse
+  if req.Id == 0 {
+    resp.Err = "No user id specified."
+    return resp
+  }
+  user, err := s.getUser(req.Id)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  // An empty name is how the client asks for its current name.
+  if req.Name != "" && req.Name != user.Name {
+    user.Name = req.Name
+    err = s.store.PutUser(user)
+    if err != nil {
+      resp.Err = err.Error()
+      return resp
+    }
+  }
+  resp.User = *user
+  return resp
+}
+
+func (s *Server) newGame(req mrgnet.NewGameRequest) mrgnet.NewGameResponse {
+  var resp mrgnet.NewGameResponse
+  if req.Id == 0 {
+    resp.Err = "No user id specified."
+    return resp
+  }
+  if req.Turn_deadline < 0 {
+    resp.Err = "Invalid turn deadline."
+    return resp
+  }
+  user, err := s.getUser(req.Id)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  key, err := s.store.NewGameKey()
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  var game mrgnet.Game
+  game.Name = fmt.Sprintf("%s's game", user.Name)
+  game.Created = time.Now()
+  game.Denizens_id = user.Id
+  game.Denizens_name = user.Name
+  game.Turn_deadline = req.Turn_deadline
+  err = s.store.PutGame(key, &game)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  resp.Name = game.Name
+  resp.Game_key = key
+  return resp
+}
+
+// If req.Unstarted is set this lists all games that are waiting for an
+// opponent, other than the requester's own.  Otherwise it lists all of the
+// unfinished games that the requester is playing in.
+func (s *Server) listGames(req mrgnet.ListGamesRequest) mrgnet.ListGamesResponse {
+  var resp mrgnet.ListGamesResponse
+  keys, err := s.store.GameKeys()
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  for _, key := range keys {
+    game, err := s.getGame(key)
+    if err != nil {
+      s.logf("Unable to load game %s: %v", key, err)
+      continue
+    }
+    if game == nil || game.Winner != 0 {
+      continue
+    }
+    var include bool
+    if req.Spectate {
+      include = canSpectate(game, req.Id)
+    } else if req.Unstarted {
+      include = game.Intruders_id == 0 && game.Denizens_id != req.Id
+    } else {
+      include = isPlayer(game, req.Id)
+    }
+    if include {
+      resp.Games = append(resp.Games, sizesOnly(game))
+      resp.Game_keys = append(resp.Game_keys, key)
+    }
+  }
+  return resp
+}
+
+func (s *Server) joinGame(req mrgnet.JoinGameRequest) mrgnet.JoinGameResponse {
+  var resp mrgnet.JoinGameResponse
+  game, err := s.getGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  switch {
+  case game == nil:
+    resp.Err = "No such game."
+    return resp
+  case req.Id == 0:
+    resp.Err = "No user id specified."
+    return resp
+  case game.Denizens_id == req.Id:
+    resp.Err = "Can't join your own game."
+    return resp
+  case game.Intruders_id != 0:
+    resp.Err = "That game already has two players."
+    return resp
+  }
+  user, err := s.getUser(req.Id)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  game.Intruders_id = user.Id
+  game.Intruders_name = user.Name
+  game.Turn_started = time.Now()
+  err = s.store.PutGame(req.Game_key, game)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  s.notify(req.Game_key)
+  resp.Successful = true
+  return resp
+}
+
+// Playback data is indexed by turn, and each round has one turn for each
+// side, the Denizens going first.
+func playbackIndex(round int, intruders bool) int {
+  index := 2 * round
+  if intruders {
+    index++
+  }
+  return index
+}
+
+// Sets (*list)[index] = data, appending if index is one past the end.  It is
+// an error to leave a gap in the list.
+func setPlayback(list *[][]byte, index int, data []byte) error {
+  switch {
+  case index < 0 || index > len(*list):
+    return fmt.Errorf("Can't set playback %d, there are only %d.", index, len(*list))
+  case index == len(*list):
+    *list = append(*list, data)
+  default:
+    (*list)[index] = data
+  }
+  return nil
+}
+
+func (s *Server) updateGame(req mrgnet.UpdateGameRequest) mrgnet.UpdateGameResponse {
+  var resp mrgnet.UpdateGameResponse
+  game, err := s.getGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  if game == nil {
+    resp.Err = "No such game."
+    return resp
+  }
+  if !isPlayer(game, req.Id) {
+    resp.Err = "You aren't playing in this game."
+    return resp
+  }
+  if game.Winner != 0 {
+    resp.Err = "This game is already over."
+    return resp
+  }
+
+  if req.Script != nil {
+    // The script is set once by whoever starts the game, after that everyone
+    // plays whatever was set.
+    if game.Script == nil {
+      game.Script = req.Script
+    }
+  }
+
+  if req.Before != nil || req.Execs != nil || req.After != nil {
+    if (req.Id == game.Intruders_id) != req.Intruders {
+      resp.Err = "Can't update the other side's turn."
+      return resp
+    }
+    index := playbackIndex(req.Round, req.Intruders)
+    if err := s.verifyTurn(game, index, req); err != nil {
+      resp.Err = err.Error()
+      return resp
+    }
+    if req.Before != nil {
+      if err := setPlayback(&game.Before, index, req.Before); err != nil {
+        resp.Err = err.Error()
+        return resp
+      }
+    }
+    if req.Execs != nil {
+      if err := setPlayback(&game.Execs, index, req.Execs); err != nil {
+        resp.Err = err.Error()
+        return resp
+      }
+      // These are kept in step with Execs even if the client didn't send
+      // them, so that they don't end up with any gaps.
+      if err := setPlayback(&game.Checksums, index, req.Checksum); err != nil {
+        resp.Err = err.Error()
+        return resp
+      }
+      if err := setPlayback(&game.Summaries, index, req.Summary); err != nil {
+        resp.Err = err.Error()
+        return resp
+      }
+      // Once the execs are in the turn is over and the other player's
+      // deadline starts.
+      game.Turn_started = time.Now()
+    }
+    if req.After != nil {
+      if err := setPlayback(&game.After, index, req.After); err != nil {
+        resp.Err = err.Error()
+        return resp
+      }
+    }
+  }
+
+  err = s.store.PutGame(req.Game_key, game)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  s.notify(req.Game_key)
+  return resp
+}
+
+func (s *Server) status(req mrgnet.StatusRequest) mrgnet.StatusResponse {
+  var resp mrgnet.StatusResponse
+  game, err := s.getGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  if game == nil {
+    resp.Err = "No such game."
+    return resp
+  }
+  if !isPlayer(game, req.Id) && !canSpectate(game, req.Id) {
+    resp.Err = "You aren't playing in this game."
+    return resp
+  }
+  if req.Sizes_only {
+    g := sizesOnly(game)
+    game = &g
+  }
+  resp.Game = game
+  return resp
+}
+
+func (s *Server) chat(req mrgnet.ChatRequest) mrgnet.ChatResponse {
+  var resp mrgnet.ChatResponse
+  game, err := s.getGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  if game == nil {
+    resp.Err = "No such game."
+    return resp
+  }
+  if !isPlayer(game, req.Id) {
+    resp.Err = "You aren't playing in this game."
+    return resp
+  }
+  text := strings.TrimSpace(req.Text)
+  if len(text) > max_chat_length {
+    // Back up to the start of a character so we don't cut one in half.
+    end := max_chat_length
+    for end > 0 && !utf8.RuneStart(text[end]) {
+      end--
+    }
+    text = text[0:end]
+  }
+  if text != "" {
+    msg := mrgnet.ChatMessage{Id: req.Id, Time: time.Now(), Text: text}
+    if req.Id == game.Denizens_id {
+      msg.Name = game.Denizens_name
+    } else {
+      msg.Name = game.Intruders_name
+    }
+    if game.Chat == nil {
+      game.Chat = make(map[int][]mrgnet.ChatMessage)
+    }
+    // Each round is a turn by the denizens followed by one by the intruders.
+    round := len(game.Execs)/2 + 1
+    game.Chat[round] = append(game.Chat[round], msg)
+    err = s.store.PutGame(req.Game_key, game)
+    if err != nil {
+      resp.Err = err.Error()
+      return resp
+    }
+  }
+  resp.Chat = game.Chat
+  return resp
+}
+
+func (s *Server) kill(req mrgnet.KillRequest) mrgnet.KillResponse {
+  var resp mrgnet.KillResponse
+  game, err := s.getGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  if game == nil {
+    resp.Err = "No such game."
+    return resp
+  }
+  if !isPlayer(game, req.Id) {
+    resp.Err = "You aren't playing in this game."
+    return resp
+  }
+  err = s.store.DeleteGame(req.Game_key)
+  if err != nil {
+    resp.Err = err.Error()
+    return resp
+  }
+  s.notify(req.Game_key)
+  return resp
+}
+
+// Wakes up all wait requests on the specified game.  s.mutex must be held.
+func (s *Server) notify(key mrgnet.GameKey) {
+  for _, c := range s.watchers[key] {
+    close(c)
+  }
+  delete(s.watchers, key)
+}
+
+func (s *Server) wait(req mrgnet.WaitRequest) mrgnet.WaitResponse {
+  var resp mrgnet.WaitResponse
+  timeout := req.Timeout
+  if timeout > max_wait {
+    timeout = max_wait
+  }
+  deadline := time.After(timeout)
+  for {
+    s.mutex.Lock()
+    game, err := s.getGame(req.Game_key)
+    switch {
+    case err != nil:
+      resp.Err = err.Error()
+    case game == nil:
+      resp.Err = "No such game."
+    case !isPlayer(game, req.Id) && !canSpectate(game, req.Id):
+      resp.Err = "You aren't playing in this game."
+    case game.Winner != 0 || (len(game.Before) >= req.Turns && len(game.Execs) >= req.Turns):
+      resp.Ready = true
+      g := sizesOnly(game)
+      resp.Game = &g
+    }
+    if resp.Err != "" || resp.Ready {
+      s.mutex.Unlock()
+      return resp
+    }
+    c := make(chan struct{})
+    s.watchers[req.Game_key] = append(s.watchers[req.Game_key], c)
+    s.mutex.Unlock()
+
+    select {
+    case <-c:
+    case <-deadline:
+      s.mutex.Lock()
+      watchers := s.watchers[req.Game_key]
+      for i := range watchers {
+        if watchers[i] == c {
+          s.watchers[req.Game_key] = append(watchers[:i], watchers[i+1:]...)
+          break
+        }
+      }
+      s.mutex.Unlock()
+      return resp
+    }
+  }
+}
+
+func isPlayer(game *mrgnet.Game, id mrgnet.NetId) bool {
+  return id != 0 && (game.Denizens_id == id || game.Intruders_id == id)
+}
+
+// Spectators can watch any game that has both of its players, but only
+// through status and wait, they can never change it.
+func canSpectate(game *mrgnet.Game, id mrgnet.NetId) bool {
+  return id != 0 && !isPlayer(game, id) && game.Denizens_id != 0 && game.Intruders_id != 0
+}
+
+// Returns a copy of game with all of the playback data emptied out.  The
+// number of entries is preserved since that is how clients tell how far
+// along a game is.
+func sizesOnly(game *mrgnet.Game) mrgnet.Game {
+  g := *game
+  g.Before = make([][]byte, len(game.Before))
+  g.Execs = make([][]byte, len(game.Execs))
+  g.After = make([][]byte, len(game.After))
+  g.Summaries = make([][]byte, len(game.Summaries))
+  g.Script = nil
+  return g
+}
@@ -0,0 +1,423 @@
+package server_test
+
+import (
+  "bytes"
+  "errors"
+  "net/http"
+  "net/url"
+  "io/ioutil"
+  "net/http/httptest"
+  "os"
+  "reflect"
+  "strings"
+  "time"
+  "github.com/orfjackal/gospec/src/gospec"
+  . "github.com/orfjackal/gospec/src/gospec"
+  "github.com/MobRulesGames/haunts/mrgnet"
+  "github.com/MobRulesGames/haunts/mrgnet/server"
+  "unicode/utf8"
+)
+
+// Only accepts turns where the after state is the before state followed by
+// the execs.
+type concatVerifier struct{}
+
+func (concatVerifier) VerifyTurn(game *mrgnet.Game, before, execs, after []byte) error {
+  if string(after) != string(before)+string(execs) {
+    return errors.New("Turn doesn't add up.")
+  }
+  return nil
+}
+
+var identities = make(map[mrgnet.NetId]*mrgnet.Identity)
+
+// Points mrgnet at ts and signs all subsequent requests as id.
+func connectAs(ts *httptest.Server, id mrgnet.NetId) error {
+  err := mrgnet.Configure(mrgnet.Config{Host_url: ts.URL})
+  if err != nil {
+    return err
+  }
+  if _, ok := identities[id]; !ok {
+    identities[id], err = mrgnet.MakeIdentity(id)
+    if err != nil {
+      return err
+    }
+  }
+  mrgnet.SetIdentity(identities[id])
+  return nil
+}
+
+// Sends a request to ts signed as whoever the request's Id field says it is
+// from.
+func doAction(ts *httptest.Server, name string, input, output interface{}) error {
+  id := mrgnet.NetId(reflect.ValueOf(input).FieldByName("Id").Int())
+  err := connectAs(ts, id)
+  if err != nil {
+    return err
+  }
+  return mrgnet.DoAction(name, input, output)
+}
+
+func ServerSpec(c gospec.Context) {
+  dir, err := ioutil.TempDir("", "mrgserver")
+  c.Assume(err, Equals, nil)
+  defer os.RemoveAll(dir)
+  store, err := server.MakeStore(dir)
+  c.Assume(err, Equals, nil)
+  s := server.MakeServer(store)
+  ts := httptest.NewServer(s)
+  defer ts.Close()
+
+  const denizen = mrgnet.NetId(1234)
+  const intruder = mrgnet.NetId(5678)
+  const spectator = mrgnet.NetId(9012)
+
+  c.Specify("Users can set and get their names.", func() {
+    var resp mrgnet.UpdateUserResponse
+    err := doAction(ts, "user", mrgnet.UpdateUserRequest{Id: denizen, Name: "Dracula"}, &resp)
+    c.Assume(err, Equals, nil)
+    c.Expect(resp.Err, Equals, "")
+    var resp2 mrgnet.UpdateUserResponse
+    err = doAction(ts, "user", mrgnet.UpdateUserRequest{Id: denizen}, &resp2)
+    c.Assume(err, Equals, nil)
+    c.Expect(resp2.Name, Equals, "Dracula")
+  })
+
+  c.Specify("A game can be created, joined, played and killed.", func() {
+    var newResp mrgnet.NewGameResponse
+    err := doAction(ts, "new", mrgnet.NewGameRequest{Id: denizen}, &newResp)
+    c.Assume(err, Equals, nil)
+    c.Assume(newResp.Err, Equals, "")
+    key := newResp.Game_key
+
+    var list mrgnet.ListGamesResponse
+    err = doAction(ts, "list", mrgnet.ListGamesRequest{Id: intruder, Unstarted: true}, &list)
+    c.Assume(err, Equals, nil)
+    c.Expect(len(list.Game_keys), Equals, 1)
+
+    var join mrgnet.JoinGameResponse
+    err = doAction(ts, "join", mrgnet.JoinGameRequest{Id: intruder, Game_key: key}, &join)
+    c.Assume(err, Equals, nil)
+    c.Expect(join.Successful, Equals, true)
+
+    var list2 mrgnet.ListGamesResponse
+    err = doAction(ts, "list", mrgnet.ListGamesRequest{Id: intruder, Unstarted: true}, &list2)
+    c.Assume(err, Equals, nil)
+    c.Expect(len(list2.Game_keys), Equals, 0)
+
+    var update mrgnet.UpdateGameResponse
+    req := mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Before: []byte("before")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Equals, "")
+    req = mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Execs: []byte("execs"), After: []byte("after"), Checksum: []byte("sum")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Equals, "")
+
+    // The intruders can't play the denizens' turn.
+    req = mrgnet.UpdateGameRequest{Id: intruder, Game_key: key, Before: []byte("before")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Not(Equals), "")
+
+    var status mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: intruder, Game_key: key}, &status)
+    c.Assume(err, Equals, nil)
+    c.Assume(status.Game, Not(IsNil))
+    c.Expect(status.Game.Intruders_id, Equals, intruder)
+    c.Expect(len(status.Game.Execs), Equals, 1)
+    c.Expect(string(status.Game.After[0]), Equals, "after")
+    c.Expect(string(status.Game.Checksums[0]), Equals, "sum")
+
+    var kill mrgnet.KillResponse
+    err = doAction(ts, "kill", mrgnet.KillRequest{Id: denizen, Game_key: key}, &kill)
+    c.Assume(err, Equals, nil)
+    c.Expect(kill.Err, Equals, "")
+    var status2 mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: intruder, Game_key: key}, &status2)
+    c.Assume(err, Equals, nil)
+    c.Expect(status2.Err, Not(Equals), "")
+  })
+  c.Specify("Spectators can watch games in progress but not play them.", func() {
+    var newResp mrgnet.NewGameResponse
+    err := doAction(ts, "new", mrgnet.NewGameRequest{Id: denizen}, &newResp)
+    c.Assume(err, Equals, nil)
+    key := newResp.Game_key
+
+    var status mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: spectator, Game_key: key}, &status)
+    c.Assume(err, Equals, nil)
+    c.Expect(status.Err, Not(Equals), "")
+
+    var join mrgnet.JoinGameResponse
+    err = doAction(ts, "join", mrgnet.JoinGameRequest{Id: intruder, Game_key: key}, &join)
+    c.Assume(err, Equals, nil)
+    c.Assume(join.Successful, Equals, true)
+
+    var list mrgnet.ListGamesResponse
+    err = doAction(ts, "list", mrgnet.ListGamesRequest{Id: spectator, Spectate: true}, &list)
+    c.Assume(err, Equals, nil)
+    c.Expect(list.Game_keys, Contains, key)
+    var list2 mrgnet.ListGamesResponse
+    err = doAction(ts, "list", mrgnet.ListGamesRequest{Id: intruder, Spectate: true}, &list2)
+    c.Assume(err, Equals, nil)
+    c.Expect(list2.Game_keys, Not(Contains), key)
+
+    var update mrgnet.UpdateGameResponse
+    req := mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Before: []byte("before")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    var status2 mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: spectator, Game_key: key}, &status2)
+    c.Assume(err, Equals, nil)
+    c.Assume(status2.Err, Equals, "")
+    c.Expect(string(status2.Game.Before[0]), Equals, "before")
+
+    req = mrgnet.UpdateGameRequest{Id: spectator, Game_key: key, Execs: []byte("execs")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Not(Equals), "")
+  })
+  c.Specify("Players can chat, spectators can only read it.", func() {
+    var newResp mrgnet.NewGameResponse
+    err := doAction(ts, "new", mrgnet.NewGameRequest{Id: denizen}, &newResp)
+    c.Assume(err, Equals, nil)
+    key := newResp.Game_key
+    var join mrgnet.JoinGameResponse
+    err = doAction(ts, "join", mrgnet.JoinGameRequest{Id: intruder, Game_key: key}, &join)
+    c.Assume(err, Equals, nil)
+
+    var chat mrgnet.ChatResponse
+    err = doAction(ts, "chat", mrgnet.ChatRequest{Id: denizen, Game_key: key, Text: "Boo!"}, &chat)
+    c.Assume(err, Equals, nil)
+    c.Expect(chat.Err, Equals, "")
+    c.Assume(len(chat.Chat[1]), Equals, 1)
+    c.Expect(chat.Chat[1][0].Text, Equals, "Boo!")
+    c.Expect(chat.Chat[1][0].Id, Equals, denizen)
+
+    // Long messages are cut short without splitting a character.
+    long := "a" + strings.Repeat("\u00e9", 300)
+    var chat_long mrgnet.ChatResponse
+    err = doAction(ts, "chat", mrgnet.ChatRequest{Id: intruder, Game_key: key, Text: long}, &chat_long)
+    c.Assume(err, Equals, nil)
+    c.Assume(len(chat_long.Chat[1]), Equals, 2)
+    c.Expect(utf8.ValidString(chat_long.Chat[1][1].Text), Equals, true)
+    c.Expect(chat_long.Chat[1][1].Text, Equals, "a"+strings.Repeat("\u00e9", 249))
+
+    var chat2 mrgnet.ChatResponse
+    err = doAction(ts, "chat", mrgnet.ChatRequest{Id: spectator, Game_key: key, Text: "Hi"}, &chat2)
+    c.Assume(err, Equals, nil)
+    c.Expect(chat2.Err, Not(Equals), "")
+
+    var status mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: spectator, Game_key: key, Sizes_only: true}, &status)
+    c.Assume(err, Equals, nil)
+    c.Assume(status.Game, Not(IsNil))
+    c.Expect(len(status.Game.Chat[1]), Equals, 2)
+  })
+  c.Specify("Players that miss their turn deadline forfeit.", func() {
+    var newResp mrgnet.NewGameResponse
+    req := mrgnet.NewGameRequest{Id: denizen, Turn_deadline: 50 * time.Millisecond}
+    err := doAction(ts, "new", req, &newResp)
+    c.Assume(err, Equals, nil)
+    key := newResp.Game_key
+
+    // The deadline doesn't start until there is an opponent.
+    time.Sleep(100 * time.Millisecond)
+    var join mrgnet.JoinGameResponse
+    err = doAction(ts, "join", mrgnet.JoinGameRequest{Id: intruder, Game_key: key}, &join)
+    c.Assume(err, Equals, nil)
+    c.Assume(join.Successful, Equals, true)
+
+    var update mrgnet.UpdateGameResponse
+    update_req := mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Before: []byte("before"), Execs: []byte("execs")}
+    err = doAction(ts, "update", update_req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Equals, "")
+
+    time.Sleep(100 * time.Millisecond)
+    var status mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: denizen, Game_key: key, Sizes_only: true}, &status)
+    c.Assume(err, Equals, nil)
+    c.Assume(status.Game, Not(IsNil))
+    c.Expect(status.Game.Turn_deadline, Equals, 50*time.Millisecond)
+    c.Expect(status.Game.Winner, Equals, denizen)
+    c.Expect(status.Game.Forfeit, Equals, true)
+
+    update_req = mrgnet.UpdateGameRequest{Id: intruder, Game_key: key, Intruders: true, Before: []byte("before")}
+    err = doAction(ts, "update", update_req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Not(Equals), "")
+  })
+  c.Specify("Turns that don't verify are rejected.", func() {
+    s.Verifier = concatVerifier{}
+    defer func() { s.Verifier = nil }()
+    var newResp mrgnet.NewGameResponse
+    err := doAction(ts, "new", mrgnet.NewGameRequest{Id: denizen}, &newResp)
+    c.Assume(err, Equals, nil)
+    key := newResp.Game_key
+
+    var update mrgnet.UpdateGameResponse
+    req := mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Before: []byte("before")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Equals, "")
+
+    req = mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Execs: []byte("teleport"), After: []byte("somewhere else")}
+    err = doAction(ts, "update", req, &update)
+    c.Assume(err, Equals, nil)
+    c.Expect(update.Err, Not(Equals), "")
+
+    var status mrgnet.StatusResponse
+    err = doAction(ts, "status", mrgnet.StatusRequest{Id: denizen, Game_key: key}, &status)
+    c.Assume(err, Equals, nil)
+    c.Assume(status.Game, Not(IsNil))
+    c.Expect(len(status.Game.Execs), Equals, 0)
+
+    var update2 mrgnet.UpdateGameResponse
+    req = mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Execs: []byte(" walk"), After: []byte("before walk")}
+    err = doAction(ts, "update", req, &update2)
+    c.Assume(err, Equals, nil)
+    c.Expect(update2.Err, Equals, "")
+  })
+  c.Specify("Compatible players are matched with each other.", func() {
+    match := func(req mrgnet.MatchRequest, resp *mrgnet.MatchResponse, done chan bool) {
+      err := connectAs(ts, req.Id)
+      if err == nil {
+        err = mrgnet.DoLongPoll("match", req, resp, req.Timeout)
+      }
+      done <- err == nil
+    }
+    req := mrgnet.MatchRequest{Id: denizen, Side: "Intruders", Map: "Lvl02.lua", Timeout: time.Second}
+    var resp mrgnet.MatchResponse
+    done := make(chan bool)
+    go match(req, &resp, done)
+    c.Assume(<-done, Equals, true)
+    c.Expect(resp.Matched, Equals, false)
+
+    // Still queued, so this player wants the other side and doesn't care
+    // about the map.
+    other := mrgnet.MatchRequest{Id: intruder, Side: "Intruders", Timeout: time.Second}
+    var other_resp mrgnet.MatchResponse
+    go match(other, &other_resp, done)
+    c.Assume(<-done, Equals, true)
+    c.Expect(other_resp.Matched, Equals, false)
+
+    other.Side = ""
+    go match(other, &other_resp, done)
+    c.Assume(<-done, Equals, true)
+    c.Assume(other_resp.Matched, Equals, true)
+    c.Expect(other_resp.Side, Equals, "Denizens")
+    c.Expect(other_resp.Map, Equals, "Lvl02.lua")
+
+    go match(req, &resp, done)
+    c.Assume(<-done, Equals, true)
+    c.Assume(resp.Matched, Equals, true)
+    c.Expect(resp.Side, Equals, "Intruders")
+    c.Expect(resp.Game_key, Equals, other_resp.Game_key)
+
+    var status mrgnet.StatusResponse
+    err := doAction(ts, "status", mrgnet.StatusRequest{Id: denizen, Game_key: resp.Game_key}, &status)
+    c.Assume(err, Equals, nil)
+    c.Assume(status.Game, Not(IsNil))
+    c.Expect(status.Game.Denizens_id, Equals, intruder)
+    c.Expect(status.Game.Intruders_id, Equals, denizen)
+    c.Expect(status.Game.Turn_deadline > 0, Equals, true)
+  })
+  c.Specify("Clients speaking a different protocol are told so.", func() {
+    req := mrgnet.Envelope{Protocol: mrgnet.Protocol_version + 1}
+    req.Encode(mrgnet.UpdateUserRequest{Id: denizen})
+    buf := bytes.NewBuffer(nil)
+    c.Assume(mrgnet.WriteEnvelope(buf, req), Equals, nil)
+    r, err := http.PostForm(ts.URL+"/user", url.Values{"data": []string{buf.String()}})
+    c.Assume(err, Equals, nil)
+    defer r.Body.Close()
+    resp, err := mrgnet.ReadEnvelope(r.Body)
+    c.Assume(err, Equals, nil)
+    c.Expect(resp.Protocol, Equals, mrgnet.Protocol_version)
+    c.Expect(resp.Err, Not(Equals), "")
+  })
+
+  c.Specify("Garbage responses are reported as errors.", func() {
+    garbage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      w.Write([]byte("this is not gzip"))
+    }))
+    defer garbage.Close()
+    var resp mrgnet.UpdateUserResponse
+    err := doAction(garbage, "user", mrgnet.UpdateUserRequest{Id: denizen}, &resp)
+    _, ok := err.(*mrgnet.DecodeError)
+    c.Expect(ok, Equals, true)
+  })
+  c.Specify("Only actions the server doesn't know about are unsupported.", func() {
+    var resp mrgnet.UpdateUserResponse
+    err := doAction(ts, "frobnicate", mrgnet.UpdateUserRequest{Id: denizen}, &resp)
+    serr, ok := err.(*mrgnet.ServerError)
+    c.Assume(ok, Equals, true)
+    c.Expect(serr.Unsupported(), Equals, true)
+
+    var wait mrgnet.WaitResponse
+    c.Assume(connectAs(ts, intruder), Equals, nil)
+    mrgnet.SetIdentity(identities[denizen])
+    req := mrgnet.WaitRequest{Id: intruder, Turns: 1, Timeout: 10 * time.Millisecond}
+    err = mrgnet.DoLongPoll("wait", req, &wait, req.Timeout)
+    serr, ok = err.(*mrgnet.ServerError)
+    c.Assume(ok, Equals, true)
+    c.Expect(serr.Unsupported(), Equals, false)
+  })
+
+  c.Specify("Wait requests return as soon as the turn arrives.", func() {
+    var newResp mrgnet.NewGameResponse
+    err := doAction(ts, "new", mrgnet.NewGameRequest{Id: denizen}, &newResp)
+    c.Assume(err, Equals, nil)
+    key := newResp.Game_key
+    var join mrgnet.JoinGameResponse
+    err = doAction(ts, "join", mrgnet.JoinGameRequest{Id: intruder, Game_key: key}, &join)
+    c.Assume(err, Equals, nil)
+
+    var timeout mrgnet.WaitResponse
+    req := mrgnet.WaitRequest{Id: intruder, Game_key: key, Turns: 1, Timeout: 10 * time.Millisecond}
+    c.Assume(connectAs(ts, intruder), Equals, nil)
+    err = mrgnet.DoLongPoll("wait", req, &timeout, req.Timeout)
+    c.Assume(err, Equals, nil)
+    c.Expect(timeout.Ready, Equals, false)
+
+    go func() {
+      time.Sleep(50 * time.Millisecond)
+      var update mrgnet.UpdateGameResponse
+      doAction(ts, "update", mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Before: []byte("before")}, &update)
+      doAction(ts, "update", mrgnet.UpdateGameRequest{Id: denizen, Game_key: key, Execs: []byte("execs"), After: []byte("after")}, &update)
+    }()
+    var resp mrgnet.WaitResponse
+    req.Timeout = 5 * time.Second
+    start := time.Now()
+    c.Assume(connectAs(ts, intruder), Equals, nil)
+    err = mrgnet.DoLongPoll("wait", req, &resp, req.Timeout)
+    c.Assume(err, Equals, nil)
+    c.Expect(resp.Ready, Equals, true)
+    c.Expect(time.Since(start) < req.Timeout, Equals, true)
+    c.Assume(resp.Game, Not(IsNil))
+    c.Expect(len(resp.Game.Execs), Equals, 1)
+  })
+  c.Specify("Only the owner of an id can act as it.", func() {
+    var resp mrgnet.UpdateUserResponse
+    err := doAction(ts, "user", mrgnet.UpdateUserRequest{Id: denizen, Name: "Dracula"}, &resp)
+    c.Assume(err, Equals, nil)
+
+    impostor, err := mrgnet.MakeIdentity(denizen)
+    c.Assume(err, Equals, nil)
+    mrgnet.SetIdentity(impostor)
+    err = mrgnet.DoAction("user", mrgnet.UpdateUserRequest{Id: denizen, Name: "Van Helsing"}, &resp)
+    _, ok := err.(*mrgnet.ServerError)
+    c.Expect(ok, Equals, true)
+
+    // A backed up identity works just as well as the original.
+    restored, err := mrgnet.UnmarshalIdentity(identities[denizen].Marshal())
+    c.Assume(err, Equals, nil)
+    mrgnet.SetIdentity(restored)
+    var resp2 mrgnet.UpdateUserResponse
+    err = mrgnet.DoAction("user", mrgnet.UpdateUserRequest{Id: denizen}, &resp2)
+    c.Assume(err, Equals, nil)
+    c.Expect(resp2.Name, Equals, "Dracula")
+  })
+}
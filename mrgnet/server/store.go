@@ -0,0 +1,159 @@
+package server
+
+import (
+  "crypto/rand"
+  "encoding/gob"
+  "encoding/hex"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// A Store keeps users and games as individual gob files underneath a single
+// directory:
+//   <dir>/users/<id>.user
+//   <dir>/games/<key>.game
+// The playback blobs of a game are kept inline in its file, so a game file
+// can get fairly large, but it means a game can be copied around or deleted
+// by just touching one file.
+type Store struct {
+  dir string
+}
+
+func MakeStore(dir string) (*Store, error) {
+  for _, sub := range []string{"users", "games"} {
+    err := os.MkdirAll(filepath.Join(dir, sub), 0755)
+    if err != nil {
+      return nil, err
+    }
+  }
+  return &Store{dir: dir}, nil
+}
+
+func (s *Store) userPath(id mrgnet.NetId) string {
+  return filepath.Join(s.dir, "users", fmt.Sprintf("%d.user", id))
+}
+
+func (s *Store) gamePath(key mrgnet.GameKey) string {
+  return filepath.Join(s.dir, "games", fmt.Sprintf("%s.game", key))
+}
+
+// Returns the user with the specified id, or nil if no such user has been
+// stored yet.
+func (s *Store) GetUser(id mrgnet.NetId) (*mrgnet.User, error) {
+  var user mrgnet.User
+  err := load(s.userPath(id), &user)
+  if os.IsNotExist(err) {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return &user, nil
+}
+
+func (s *Store) PutUser(user *mrgnet.User) error {
+  return save(s.userPath(user.Id), user)
+}
+
+// Returns the game with the specified key, or nil if no such game exists.
+func (s *Store) GetGame(key mrgnet.GameKey) (*mrgnet.Game, error) {
+  if !validKey(key) {
+    return nil, nil
+  }
+  var game mrgnet.Game
+  err := load(s.gamePath(key), &game)
+  if os.IsNotExist(err) {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return &game, nil
+}
+
+func (s *Store) PutGame(key mrgnet.GameKey, game *mrgnet.Game) error {
+  if !validKey(key) {
+    return fmt.Errorf("Invalid game key '%s'.", key)
+  }
+  return save(s.gamePath(key), game)
+}
+
+func (s *Store) DeleteGame(key mrgnet.GameKey) error {
+  if !validKey(key) {
+    return fmt.Errorf("Invalid game key '%s'.", key)
+  }
+  return os.Remove(s.gamePath(key))
+}
+
+// Returns the keys of all games currently stored.
+func (s *Store) GameKeys() ([]mrgnet.GameKey, error) {
+  names, err := filepath.Glob(filepath.Join(s.dir, "games", "*.game"))
+  if err != nil {
+    return nil, err
+  }
+  var keys []mrgnet.GameKey
+  for _, name := range names {
+    keys = append(keys, mrgnet.GameKey(strings.TrimSuffix(filepath.Base(name), ".game")))
+  }
+  return keys, nil
+}
+
+// Makes a new game key that isn't used by any game in the store.
+func (s *Store) NewGameKey() (mrgnet.GameKey, error) {
+  for {
+    b := make([]byte, 16)
+    _, err := rand.Read(b)
+    if err != nil {
+      return "", err
+    }
+    key := mrgnet.GameKey(hex.EncodeToString(b))
+    if _, err := os.Stat(s.gamePath(key)); os.IsNotExist(err) {
+      return key, nil
+    }
+  }
+}
+
+// Game keys come straight from clients and are used as file names, so we
+// only allow keys that look like the ones we make in NewGameKey.
+func validKey(key mrgnet.GameKey) bool {
+  if len(key) == 0 {
+    return false
+  }
+  for _, c := range key {
+    if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+      return false
+    }
+  }
+  return true
+}
+
+func load(path string, target interface{}) error {
+  f, err := os.Open(path)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+  return gob.NewDecoder(f).Decode(target)
+}
+
+// Writes to a temporary file and then renames it so that a crash in the
+// middle of a write never leaves a half-written game behind.
+func save(path string, source interface{}) error {
+  tmp := path + ".tmp"
+  f, err := os.Create(tmp)
+  if err != nil {
+    return err
+  }
+  err = gob.NewEncoder(f).Encode(source)
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    os.Remove(tmp)
+    return err
+  }
+  return os.Rename(tmp, path)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+  "errors"
+  "github.com/MobRulesGames/haunts/mrgnet"
+)
+
+// A Verifier checks that a turn is legal before the server accepts it.  The
+// server only ever sees opaque blobs, so it can't tell that a modified client
+// moved a unit through a wall or spent AP it didn't have, but something that
+// knows the rules can replay execs against before and check that it ends up
+// with after.
+type Verifier interface {
+  // Returns an error if playing execs from before doesn't give after.  game
+  // is the game as it was before this turn was uploaded, and must not be
+  // modified.
+  VerifyTurn(game *mrgnet.Game, before, execs, after []byte) error
+}
+
+// Calls the server's Verifier, if it has one, on the turn in req.  Execs and
+// After are always uploaded together, so this is checked whenever Execs are.
+func (s *Server) verifyTurn(game *mrgnet.Game, index int, req mrgnet.UpdateGameRequest) error {
+  if s.Verifier == nil || req.Execs == nil {
+    return nil
+  }
+  before := req.Before
+  if before == nil && index < len(game.Before) {
+    before = game.Before[index]
+  }
+  if before == nil {
+    return errors.New("Can't upload execs for a turn without a starting state.")
+  }
+  if req.After == nil {
+    return errors.New("Can't upload execs without the state they lead to.")
+  }
+  if err := s.Verifier.VerifyTurn(game, before, req.Execs, req.After); err != nil {
+    s.logf("Rejected turn %d of game %s: %v", index, req.Game_key, err)
+    return err
+  }
+  return nil
+}
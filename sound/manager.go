@@ -167,6 +167,9 @@ func PlayMusic(name string) {
 }
 
 func StopMusic() {
+  if system == nil {
+    return
+  }
   music_stop <- true
 }
 
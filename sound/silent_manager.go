@@ -5,13 +5,8 @@
 
 package sound
 
-import (
-  "github.com/MobRulesGames/glop/sprite"
-  "github.com/MobRulesGames/haunts/base"
-)
-
-func Init()                                 {}
-func MapSounds(m map[string]string)         {}
-func trigger(s *sprite.Sprite, name string) {}
-func PlaySound(p base.Path)                 {}
-func SetBackgroundMusic(file string)        {}
+func Init()                                  {}
+func PlayMusic(name string)                  {}
+func StopMusic()                             {}
+func SetMusicParam(name string, val float64) {}
+func PlaySound(name string, volume float64)  {}
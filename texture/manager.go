@@ -201,12 +201,14 @@ func (m *Manager) Scavenger() {
       m.deleted[s] = m.registry[s]
       delete(m.registry, s)
     }
-    render.Queue(func() {
-      for _, d := range unused_data {
-        d.texture.Delete()
-        d.texture = 0
-      }
-    })
+    if !base.Headless() {
+      render.Queue(func() {
+        for _, d := range unused_data {
+          d.texture.Delete()
+          d.texture = 0
+        }
+      })
+    }
     m.mutex.Unlock()
   }
 }
@@ -340,7 +342,9 @@ func handleLoadRequest(req loadRequest) {
 }
 
 func (m *Manager) LoadFromPath(path string) *Data {
-  setupTextureList()
+  if !base.Headless() {
+    setupTextureList()
+  }
   m.mutex.RLock()
   var data *Data
   var ok bool
@@ -371,6 +375,12 @@ func (m *Manager) LoadFromPath(path string) *Data {
   data.dx = config.Width
   data.dy = config.Height
 
+  // Headless games only care about the dimensions of a texture, so there's
+  // no reason to decode the whole thing.
+  if base.Headless() {
+    return data
+  }
+
   load_requests <- loadRequest{path, data}
   return data
 }
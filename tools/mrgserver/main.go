@@ -0,0 +1,52 @@
+// Runs a standalone server for online games, see haunts/mrgnet/server.
+package main
+
+import (
+  "flag"
+  "fmt"
+  "log"
+  "net/http"
+  "os"
+  "time"
+  "github.com/MobRulesGames/haunts/game"
+  "github.com/MobRulesGames/haunts/mrgnet/server"
+
+  // Need to pull in all of the actions we define here and not in
+  // haunts/game because haunts/game/actions depends on it
+  _ "github.com/MobRulesGames/haunts/game/actions"
+  _ "github.com/MobRulesGames/haunts/game/ai"
+)
+
+var addr = flag.String("addr", ":8080", "Address to listen on.")
+var dir = flag.String("dir", "mrgserver-data", "Directory where users and games are stored.")
+var verbose = flag.Bool("v", false, "Log every request.")
+var match_deadline = flag.Duration("match_deadline", 10*time.Minute, "Turn deadline for games made by matchmaking, 0 for none.")
+var datadir = flag.String("data", "data", "Haunts data directory, uploaded turns are replayed with it.")
+var verify = flag.Bool("verify", true, "Reject turns that don't lead to the state that was uploaded with them.")
+
+func main() {
+  flag.Parse()
+  store, err := server.MakeStore(*dir)
+  if err != nil {
+    fmt.Printf("Unable to open store in %s: %v\n", *dir, err)
+    os.Exit(1)
+  }
+  s := server.MakeServer(store)
+  if *verbose {
+    s.Log = log.New(os.Stdout, "mrgserver> ", log.Ltime)
+  }
+  s.Match_deadline = *match_deadline
+  if *verify {
+    if err := game.SetupHeadless(*datadir); err != nil {
+      fmt.Printf("Unable to load data from %s: %v\n", *datadir, err)
+      os.Exit(1)
+    }
+    s.Verifier = &game.TurnVerifier{}
+  }
+  fmt.Printf("Serving games from %s on %s\n", *dir, *addr)
+  err = http.ListenAndServe(*addr, s)
+  if err != nil {
+    fmt.Printf("Error: %v\n", err)
+    os.Exit(1)
+  }
+}
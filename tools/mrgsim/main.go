@@ -0,0 +1,427 @@
+// Plays complete games with Ais on both sides and reports how they went, so
+// that the data files can be balanced without anyone having to play them.
+//
+//   mrgsim -data path/to/data -script Lvl01.lua -n 100 -format csv
+package main
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "runtime"
+  "sort"
+  "strings"
+  "time"
+  "github.com/MobRulesGames/haunts/base"
+  "github.com/MobRulesGames/haunts/game"
+
+  // Need to pull in all of the actions we define here and not in
+  // haunts/game because haunts/game/actions depends on it
+  _ "github.com/MobRulesGames/haunts/game/actions"
+  _ "github.com/MobRulesGames/haunts/game/ai"
+)
+
+var datadir = flag.String("data", "data", "Haunts data directory.")
+var script = flag.String("script", "Lvl01.lua", "Level script to play, relative to the scripts directory.")
+var matches = flag.Int("n", 10, "Number of matches to play.")
+var seed = flag.Int64("seed", 1, "Seed for the first match, each match after that uses the next seed.")
+var denizens_ai = flag.String("denizens", "denizens.lua", "Ai for the denizens, relative to the ais directory.")
+var intruders_ai = flag.String("intruders", "intruders.lua", "Ai for the intruders, relative to the ais directory.")
+var max_rounds = flag.Int("max-rounds", 100, "Matches still going after this many rounds are stopped and counted as unfinished.")
+var turn_timeout = flag.Duration("turn-timeout", time.Minute, "Matches that spend longer than this on one turn are stopped and counted as unfinished.")
+var format = flag.String("format", "json", "Format of the report, json or csv.")
+var out = flag.String("out", "", "File to write the report to, stdout if empty.")
+var params = paramsFlag{}
+
+func init() {
+  flag.Var(params, "param", "key=value passed to the script's Init(), can be repeated.")
+}
+
+// Milliseconds of game time that pass with each Think.
+const think_dt = 16
+
+type paramsFlag map[string]string
+
+func (p paramsFlag) String() string {
+  var kvs []string
+  for k, v := range p {
+    kvs = append(kvs, k+"="+v)
+  }
+  sort.Strings(kvs)
+  return strings.Join(kvs, ",")
+}
+func (p paramsFlag) Set(kv string) error {
+  parts := strings.SplitN(kv, "=", 2)
+  if len(parts) != 2 {
+    return fmt.Errorf("'%s' isn't of the form key=value.", kv)
+  }
+  p[parts[0]] = parts[1]
+  return nil
+}
+
+type matchResult struct {
+  Seed int64
+
+  // "Denizens", "Intruders", or empty if the match didn't finish.
+  Winner string
+  Rounds int
+}
+
+type actionStats struct {
+  Uses           int
+  Damage         int
+  Damage_per_use float64
+}
+
+type entityStats struct {
+  Actions           int
+  Damage_dealt      int
+  Damage_taken      int
+  Damage_per_action float64
+}
+
+type conditionStats struct {
+  // Number of times an entity had the condition at the start of a turn.
+  Turns int
+
+  // Turns divided by the number of times any entity started a turn.
+  Uptime float64
+}
+
+type report struct {
+  Script     string
+  Matches    int
+  Wins       map[string]int
+  Win_rate   map[string]float64
+  Avg_rounds float64
+  Actions    map[string]*actionStats
+  Entities   map[string]*entityStats
+  Conditions map[string]*conditionStats
+  Results    []matchResult
+
+  // Number of times any entity started a turn, for Conditions.
+  entity_turns int
+}
+
+func makeReport() *report {
+  return &report{
+    Script:     *script,
+    Wins:       make(map[string]int),
+    Win_rate:   make(map[string]float64),
+    Actions:    make(map[string]*actionStats),
+    Entities:   make(map[string]*entityStats),
+    Conditions: make(map[string]*conditionStats),
+  }
+}
+
+func (r *report) entity(name string) *entityStats {
+  if r.Entities[name] == nil {
+    r.Entities[name] = &entityStats{}
+  }
+  return r.Entities[name]
+}
+
+// Fills in all of the averages once every match has been played.
+func (r *report) finish() {
+  rounds := 0
+  for _, result := range r.Results {
+    rounds += result.Rounds
+  }
+  if r.Matches > 0 {
+    r.Avg_rounds = float64(rounds) / float64(r.Matches)
+    for side, wins := range r.Wins {
+      r.Win_rate[side] = float64(wins) / float64(r.Matches)
+    }
+  }
+  for _, a := range r.Actions {
+    if a.Uses > 0 {
+      a.Damage_per_use = float64(a.Damage) / float64(a.Uses)
+    }
+  }
+  for _, e := range r.Entities {
+    if e.Actions > 0 {
+      e.Damage_per_action = float64(e.Damage_dealt) / float64(e.Actions)
+    }
+  }
+  for _, c := range r.Conditions {
+    if r.entity_turns > 0 {
+      c.Uptime = float64(c.Turns) / float64(r.entity_turns)
+    }
+  }
+}
+
+// Answers for the player whenever the script asks something, and notices
+// who won from the victory dialog that every level shows at the end.
+type simInput struct {
+  game.AutoInput
+  winner string
+}
+
+func (si *simInput) Dialog(path string, args map[string]string) []string {
+  for _, side := range []string{"Denizens", "Intruders"} {
+    if strings.Contains(path, "Victory_"+side) {
+      si.winner = side
+    }
+  }
+  return nil
+}
+
+// Both sides are played by the Ais from the command line, so whenever a
+// level lets the player pick a side we play both.
+func (si *simInput) Choose(name string, options []string, min, max int) []string {
+  for _, option := range options {
+    if option == "Humans" {
+      return []string{option}
+    }
+  }
+  return si.AutoInput.Choose(name, options, min, max)
+}
+
+func (si *simInput) HumanAi(target string) string {
+  switch target {
+  case "denizen":
+    return *denizens_ai
+  case "intruder":
+    return *intruders_ai
+  }
+  return ""
+}
+
+type entSnapshot struct {
+  name string
+  hp   int
+}
+
+// Keeps track of how much damage every action does.
+type simWatcher struct {
+  r      *report
+  ent    *game.Entity
+  before map[game.EntityId]entSnapshot
+}
+
+func snapshot(g *game.Game) map[game.EntityId]entSnapshot {
+  snap := make(map[game.EntityId]entSnapshot)
+  for _, ent := range g.Ents {
+    if ent.Stats != nil {
+      snap[ent.Id] = entSnapshot{ent.Name, ent.Stats.HpCur()}
+    }
+  }
+  return snap
+}
+
+func (sw *simWatcher) ActionStarted(g *game.Game, ent *game.Entity, action game.Action) {
+  sw.ent = ent
+  sw.before = snapshot(g)
+}
+
+func (sw *simWatcher) ActionCompleted(g *game.Game, action game.Action) {
+  if sw.ent == nil {
+    return
+  }
+  after := snapshot(g)
+  damage := 0
+  for id, before := range sw.before {
+    // Ents that are gone lost everything they had.
+    lost := before.hp - after[id].hp
+    if lost <= 0 {
+      continue
+    }
+    damage += lost
+    sw.r.entity(before.name).Damage_taken += lost
+  }
+  name := action.String()
+  if sw.r.Actions[name] == nil {
+    sw.r.Actions[name] = &actionStats{}
+  }
+  sw.r.Actions[name].Uses++
+  sw.r.Actions[name].Damage += damage
+  e := sw.r.entity(sw.ent.Name)
+  e.Actions++
+  e.Damage_dealt += damage
+  sw.ent = nil
+}
+
+// Counts the conditions that every ent has at the start of a turn.
+func (r *report) sampleConditions(g *game.Game) {
+  for _, ent := range g.Ents {
+    if ent.Stats == nil {
+      continue
+    }
+    r.entity_turns++
+    for _, name := range ent.Stats.ConditionNames() {
+      if r.Conditions[name] == nil {
+        r.Conditions[name] = &conditionStats{}
+      }
+      r.Conditions[name].Turns++
+    }
+  }
+}
+
+func playMatch(r *report, seed int64) matchResult {
+  result := matchResult{Seed: seed}
+  input := &simInput{}
+  h := game.MakeHeadlessGame(*script, nil, params, input, seed)
+  defer h.Close()
+  h.Watch(&simWatcher{r: r})
+  // Nobody is watching, so every action and animation can be played out as
+  // soon as it starts.
+  h.SetClock(game.StepClock{think_dt})
+  h.SetFastForward(true)
+  turn := -1
+  turn_start := time.Now()
+  for !h.Over() {
+    h.Think(think_dt)
+    runtime.Gosched()
+    g := h.Game()
+    if g == nil {
+      if time.Since(turn_start) > *turn_timeout {
+        base.Error().Printf("Match %d never loaded a house.", seed)
+        return result
+      }
+      continue
+    }
+    result.Rounds = (g.Turn + 1) / 2
+    if g.Turn != turn {
+      turn = g.Turn
+      turn_start = time.Now()
+      r.sampleConditions(g)
+    }
+    if result.Rounds > *max_rounds {
+      base.Log().Printf("Match %d went past %d rounds.", seed, *max_rounds)
+      return result
+    }
+    if time.Since(turn_start) > *turn_timeout {
+      base.Warn().Printf("Match %d got stuck on turn %d.", seed, turn)
+      return result
+    }
+  }
+  result.Winner = input.winner
+  return result
+}
+
+func writeJson(w io.Writer, r *report) error {
+  data, err := json.MarshalIndent(r, "", "  ")
+  if err != nil {
+    return err
+  }
+  _, err = w.Write(append(data, '\n'))
+  return err
+}
+
+func sortedKeys(m interface{}) []string {
+  var keys []string
+  switch m := m.(type) {
+  case map[string]*actionStats:
+    for k := range m {
+      keys = append(keys, k)
+    }
+  case map[string]*entityStats:
+    for k := range m {
+      keys = append(keys, k)
+    }
+  case map[string]*conditionStats:
+    for k := range m {
+      keys = append(keys, k)
+    }
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// Writes the report as section,name,stat,value rows, which is easy to pivot
+// on in a spreadsheet.
+func writeCsv(w io.Writer, r *report) error {
+  cw := csv.NewWriter(w)
+  f := func(v float64) string { return fmt.Sprintf("%.4f", v) }
+  d := func(v int) string { return fmt.Sprintf("%d", v) }
+  rows := [][]string{
+    {"section", "name", "stat", "value"},
+    {"matches", r.Script, "count", d(r.Matches)},
+    {"matches", r.Script, "avg_rounds", f(r.Avg_rounds)},
+  }
+  for _, side := range []string{"Denizens", "Intruders", "Unfinished"} {
+    rows = append(rows,
+      []string{"wins", side, "count", d(r.Wins[side])},
+      []string{"wins", side, "rate", f(r.Win_rate[side])})
+  }
+  for _, name := range sortedKeys(r.Actions) {
+    a := r.Actions[name]
+    rows = append(rows,
+      []string{"action", name, "uses", d(a.Uses)},
+      []string{"action", name, "damage", d(a.Damage)},
+      []string{"action", name, "damage_per_use", f(a.Damage_per_use)})
+  }
+  for _, name := range sortedKeys(r.Entities) {
+    e := r.Entities[name]
+    rows = append(rows,
+      []string{"entity", name, "actions", d(e.Actions)},
+      []string{"entity", name, "damage_dealt", d(e.Damage_dealt)},
+      []string{"entity", name, "damage_taken", d(e.Damage_taken)},
+      []string{"entity", name, "damage_per_action", f(e.Damage_per_action)})
+  }
+  for _, name := range sortedKeys(r.Conditions) {
+    c := r.Conditions[name]
+    rows = append(rows,
+      []string{"condition", name, "turns", d(c.Turns)},
+      []string{"condition", name, "uptime", f(c.Uptime)})
+  }
+  if err := cw.WriteAll(rows); err != nil {
+    return err
+  }
+  cw.Flush()
+  return cw.Error()
+}
+
+func main() {
+  flag.Parse()
+  if *format != "json" && *format != "csv" {
+    fmt.Printf("-format must be json or csv.\n")
+    os.Exit(1)
+  }
+  err := game.SetupHeadless(*datadir)
+  if err != nil {
+    fmt.Printf("Unable to load data from %s: %v\n", *datadir, err)
+    os.Exit(1)
+  }
+
+  r := makeReport()
+  for i := 0; i < *matches; i++ {
+    result := playMatch(r, *seed+int64(i))
+    r.Matches++
+    r.Results = append(r.Results, result)
+    if result.Winner == "" {
+      r.Wins["Unfinished"]++
+    } else {
+      r.Wins[result.Winner]++
+    }
+    winner := result.Winner
+    if winner == "" {
+      winner = "nobody"
+    }
+    fmt.Fprintf(os.Stderr, "Match %d/%d (seed %d): %s won after %d rounds\n", i+1, *matches, result.Seed, winner, result.Rounds)
+  }
+  r.finish()
+
+  var w io.Writer = os.Stdout
+  if *out != "" {
+    f, err := os.Create(*out)
+    if err != nil {
+      fmt.Printf("Unable to create %s: %v\n", *out, err)
+      os.Exit(1)
+    }
+    defer f.Close()
+    w = f
+  }
+  if *format == "json" {
+    err = writeJson(w, r)
+  } else {
+    err = writeCsv(w, r)
+  }
+  if err != nil {
+    fmt.Printf("Unable to write report: %v\n", err)
+    os.Exit(1)
+  }
+}
@@ -0,0 +1,126 @@
+// Exports the game in progress in a player file, or a save, as json, and
+// imports edited json back into it.
+//
+//   mrgstate -data path/to/data export players/1234.player > state.json
+//   mrgstate -data path/to/data import players/1234.player state.json
+package main
+
+import (
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "github.com/MobRulesGames/haunts/game"
+
+  // Need to pull in all of the actions we define here and not in
+  // haunts/game because haunts/game/actions depends on it
+  _ "github.com/MobRulesGames/haunts/game/actions"
+  _ "github.com/MobRulesGames/haunts/game/ai"
+)
+
+var datadir = flag.String("data", "data", "Haunts data directory.")
+var out = flag.String("out", "", "For export, the file to write the json to, stdout if empty.  For import, the file to write the player to, the player file itself if empty.")
+
+func usage() {
+  fmt.Fprintf(os.Stderr, "Usage:\n")
+  fmt.Fprintf(os.Stderr, "  mrgstate [flags] export <player or save file>\n")
+  fmt.Fprintf(os.Stderr, "  mrgstate [flags] import <player or save file> <json file>\n")
+  flag.PrintDefaults()
+}
+
+// Saves made from the system menu, and the autosave, are .save files, anything
+// else is treated as a player file.
+func isSave(path string) bool {
+  return filepath.Ext(path) == ".save"
+}
+
+func loadPlayer(path string) (*game.Player, error) {
+  if isSave(path) {
+    return game.LoadSave(path)
+  }
+  return game.LoadPlayer(path)
+}
+
+func savePlayer(path string, p *game.Player) error {
+  if isSave(path) {
+    return game.UpdateSave(path, p)
+  }
+  f, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+  return game.EncodePlayer(f, p)
+}
+
+func export(path string) error {
+  p, err := loadPlayer(path)
+  if err != nil {
+    return err
+  }
+  js, err := game.ExportPlayerState(p)
+  if err != nil {
+    return err
+  }
+  w := os.Stdout
+  if *out != "" {
+    w, err = os.Create(*out)
+    if err != nil {
+      return err
+    }
+    defer w.Close()
+  }
+  return js.Write(w)
+}
+
+func doImport(path, json_path string) error {
+  p, err := loadPlayer(path)
+  if err != nil {
+    return err
+  }
+  f, err := os.Open(json_path)
+  if err != nil {
+    return err
+  }
+  js, err := game.ReadJsonState(f)
+  f.Close()
+  if err != nil {
+    return err
+  }
+  if err := game.ImportPlayerState(p, js); err != nil {
+    return err
+  }
+  if *out != "" {
+    path = *out
+  }
+  return savePlayer(path, p)
+}
+
+func main() {
+  flag.Usage = usage
+  flag.Parse()
+  args := flag.Args()
+  if len(args) < 2 {
+    usage()
+    os.Exit(1)
+  }
+  err := game.SetupHeadless(*datadir)
+  if err != nil {
+    fmt.Printf("Unable to load data from %s: %v\n", *datadir, err)
+    os.Exit(1)
+  }
+
+  switch {
+  case args[0] == "export" && len(args) == 2:
+    err = export(args[1])
+  case args[0] == "import" && len(args) == 3:
+    err = doImport(args[1], args[2])
+  default:
+    usage()
+    os.Exit(1)
+  }
+  if err != nil {
+    fmt.Printf("Unable to %s %s: %v\n", args[0], args[1], err)
+    os.Exit(1)
+  }
+}